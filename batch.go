@@ -0,0 +1,147 @@
+package transmission_go_api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ChunkError is the error of one chunk of a BatchDo.
+type ChunkError struct {
+	Index int     // The position of the chunk, counting from 0.
+	Ids   []int64 // The ids of the chunk.
+	Err   error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (ids %d to %d): %v", e.Index, e.Ids[0], e.Ids[len(e.Ids)-1], e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError holds the errors of the chunks of a BatchDo that failed, in
+// chunk order.
+type BatchError []*ChunkError
+
+func (e BatchError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d chunks failed, first %v", len(e), e[0])
+}
+
+func (e BatchError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// BatchDo splits ids into chunks of at most chunkSize ids and calls fn for
+// each, running up to parallel calls at once. It returns after all started
+// calls have returned. The errors of failed chunks are returned together in a
+// BatchError. Once ctx is done no more chunks are started, and each chunk not
+// started fails with the context's error.
+func BatchDo(ctx context.Context, ids []int64, chunkSize, parallel int, fn func(ctx context.Context, ids []int64) error) error {
+	if len(ids) == 0 {
+		return ErrNoIds
+	}
+	if chunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	if parallel <= 0 {
+		return fmt.Errorf("invalid parallelism %d", parallel)
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs BatchError
+	)
+	fail := func(index int, chunk []int64, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, &ChunkError{Index: index, Ids: chunk, Err: err})
+	}
+	slots := make(chan struct{}, parallel)
+	for start := 0; start < len(ids); start += chunkSize {
+		end := start + chunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		index, chunk := start/chunkSize, ids[start:end]
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			fail(index, chunk, ctx.Err())
+			continue
+		}
+		// select picks randomly when ctx is done and a slot is free.
+		if err := ctx.Err(); err != nil {
+			<-slots
+			fail(index, chunk, err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(ctx, chunk); err != nil {
+				fail(index, chunk, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	return errs
+}
+
+// StartBatched is like Start, but sends the ids in chunks of at most
+// chunkSize, up to parallel requests at once, see BatchDo.
+func (t *Transmission) StartBatched(ids []int64, chunkSize, parallel int) error {
+	return t.StartBatchedContext(context.Background(), ids, chunkSize, parallel)
+}
+
+// StartBatchedContext is like StartBatched, but with a context.
+func (t *Transmission) StartBatchedContext(ctx context.Context, ids []int64, chunkSize, parallel int) error {
+	return BatchDo(ctx, ids, chunkSize, parallel, t.StartContext)
+}
+
+// StopBatched is like Stop, but sends the ids in chunks of at most chunkSize,
+// up to parallel requests at once, see BatchDo.
+func (t *Transmission) StopBatched(ids []int64, chunkSize, parallel int) error {
+	return t.StopBatchedContext(context.Background(), ids, chunkSize, parallel)
+}
+
+// StopBatchedContext is like StopBatched, but with a context.
+func (t *Transmission) StopBatchedContext(ctx context.Context, ids []int64, chunkSize, parallel int) error {
+	return BatchDo(ctx, ids, chunkSize, parallel, t.StopContext)
+}
+
+// VerifyBatched is like Verify, but sends the ids in chunks of at most
+// chunkSize, up to parallel requests at once, see BatchDo.
+func (t *Transmission) VerifyBatched(ids []int64, chunkSize, parallel int) error {
+	return t.VerifyBatchedContext(context.Background(), ids, chunkSize, parallel)
+}
+
+// VerifyBatchedContext is like VerifyBatched, but with a context.
+func (t *Transmission) VerifyBatchedContext(ctx context.Context, ids []int64, chunkSize, parallel int) error {
+	return BatchDo(ctx, ids, chunkSize, parallel, t.VerifyContext)
+}
+
+// ReannounceBatched is like Reannounce, but sends the ids in chunks of at most
+// chunkSize, up to parallel requests at once, see BatchDo.
+func (t *Transmission) ReannounceBatched(ids []int64, chunkSize, parallel int) error {
+	return t.ReannounceBatchedContext(context.Background(), ids, chunkSize, parallel)
+}
+
+// ReannounceBatchedContext is like ReannounceBatched, but with a context.
+func (t *Transmission) ReannounceBatchedContext(ctx context.Context, ids []int64, chunkSize, parallel int) error {
+	return BatchDo(ctx, ids, chunkSize, parallel, t.ReannounceContext)
+}
@@ -0,0 +1,32 @@
+package transmission_go_api
+
+// Client is the part of the Transmission API most applications use. Code
+// taking a Client instead of a *Transmission can be tested with the fake in
+// the transmissionmock package.
+type Client interface {
+	ListAll() ([]*Torrent, error)
+	ListBrief() ([]*Torrent, error)
+	Get(ids []int64) ([]*Torrent, error)
+	GetTorrent(id int64) (*Torrent, error)
+	GetWithFields(ids []int64, fields []string) ([]*Torrent, error)
+	Start(ids []int64) error
+	StartNow(ids []int64) error
+	Stop(ids []int64) error
+	Verify(ids []int64) error
+	Reannounce(ids []int64) error
+	Remove(ids []int64) error
+	RemoveWithData(ids []int64) error
+	Add(source string) (*AddResult, error)
+	AddWithOptions(source string, opts *AddOptions) (*AddResult, error)
+	AddTorrentFile(path string) (*AddResult, error)
+	SetTorrents(ids []int64, args *TorrentSetArgs) error
+	SetLocation(ids []int64, location string, move bool) error
+	RenamePath(id int64, path string, name string) (*RenameResult, error)
+	GetSession() (*Session, error)
+	SetSession(args *SessionSetArgs) error
+	GetSessionStats() (*SessionStats, error)
+	FreeSpace(path string) (int64, error)
+	RPCVersion() (int64, error)
+}
+
+var _ Client = (*Transmission)(nil)
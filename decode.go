@@ -0,0 +1,136 @@
+package transmission_go_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// torrentFieldIndex maps the JSON names of the Torrent fields to their
+// index in the struct.
+var torrentFieldIndex = func() map[string]int {
+	index := make(map[string]int)
+	typ := reflect.TypeOf(Torrent{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		index[name] = i
+	}
+	return index
+}()
+
+// UnmarshalJSON decodes the torrent field by field. A field whose value does
+// not fit its Go type, as happens between daemon versions, is left at its
+// zero value and reported by SkippedFields instead of failing the whole
+// torrent.
+func (t *Torrent) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = Torrent{}
+	v := reflect.ValueOf(t).Elem()
+	for key, value := range raw {
+		i, ok := torrentFieldIndex[key]
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if err := json.Unmarshal(value, field.Addr().Interface()); err != nil {
+			field.Set(reflect.Zero(field.Type()))
+			t.skipped = append(t.skipped, key)
+		}
+	}
+	sort.Strings(t.skipped)
+	return nil
+}
+
+// SkippedFields returns the names of the fields that could not be decoded,
+// sorted.
+func (t *Torrent) SkippedFields() []string {
+	return t.skipped
+}
+
+// TorrentDecodeError describes a torrent of a torrent-get response that could
+// not be decoded.
+type TorrentDecodeError struct {
+	Index int             // Position in the response.
+	Raw   json.RawMessage // The torrent as received.
+	Err   error
+}
+
+// maxRawSnippet limits how much of the raw torrent an error message shows.
+const maxRawSnippet = 200
+
+func (e *TorrentDecodeError) Error() string {
+	raw := string(e.Raw)
+	if len(raw) > maxRawSnippet {
+		raw = raw[:maxRawSnippet] + "..."
+	}
+	return fmt.Sprintf("torrent %d: %v: %s", e.Index, e.Err, raw)
+}
+
+func (e *TorrentDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// TorrentDecodeErrors is returned along with the torrents that could be
+// decoded when some of a response could not.
+type TorrentDecodeErrors []*TorrentDecodeError
+
+func (e TorrentDecodeErrors) Error() string {
+	if len(e) == 1 {
+		return "torrent-get: " + e[0].Error()
+	}
+	return fmt.Sprintf("torrent-get: %d torrents could not be decoded, first %v", len(e), e[0])
+}
+
+func (e TorrentDecodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// checkStrict returns an error naming the first unknown argument, or the
+// first torrent field that is unknown, could not be decoded, or was
+// requested but is missing.
+func checkStrict(p *getResponsePayload, fields []string) error {
+	if p.unknownErr != nil {
+		return fmt.Errorf("torrent-get: %v", p.unknownErr)
+	}
+	for i, entry := range p.entries {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(entry, &raw); err != nil {
+			return err
+		}
+		var keys []string
+		for key := range raw {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := torrentFieldIndex[key]; !ok {
+				return fmt.Errorf("torrent-get: torrent %d has unknown field %q", i, key)
+			}
+		}
+		if skipped := p.Torrents[i].SkippedFields(); len(skipped) > 0 {
+			return fmt.Errorf("torrent-get: torrent %d has undecodable field %q", i, skipped[0])
+		}
+		for _, field := range fields {
+			if _, ok := raw[field]; !ok {
+				return fmt.Errorf("torrent-get: torrent %d is missing field %q", i, field)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package transmission_go_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// benchmarkTorrents returns a torrent-get response payload for n torrents
+// with the fields of ListAll, in the object or the table format.
+func benchmarkTorrents(b *testing.B, n int, table bool) []byte {
+	b.Helper()
+	var fields []string
+	for _, field := range lightFields(17) {
+		if _, ok := torrentFieldIndex[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	var rows []interface{}
+	if table {
+		rows = append(rows, fields)
+	}
+	for i := 0; i < n; i++ {
+		torrent := &Torrent{
+			Id:            int64(i + 1),
+			Name:          fmt.Sprintf("torrent number %d", i),
+			HashString:    fmt.Sprintf("%040x", i),
+			DownloadDir:   "/downloads/complete",
+			Status:        TR_STATUS_SEED,
+			PercentDone:   1,
+			TotalSize:     int64(i) << 20,
+			AddedDate:     1600000000 + int64(i),
+			RateUpload:    int64(i * 100),
+			Labels:        []string{"linux", "iso"},
+			TrackerList:   "https://tracker.example.org/announce\n",
+			UploadRatio:   1.5,
+			MagnetLink:    fmt.Sprintf("magnet:?xt=urn:btih:%040x", i),
+			QueuePosition: int64(i),
+		}
+		// Every field, as the daemon sends zero values too.
+		v := reflect.ValueOf(torrent).Elem()
+		object := map[string]json.RawMessage{}
+		var row []json.RawMessage
+		for _, field := range fields {
+			value, err := json.Marshal(v.Field(torrentFieldIndex[field]).Interface())
+			if err != nil {
+				b.Fatal(err)
+			}
+			object[field] = value
+			row = append(row, value)
+		}
+		if table {
+			rows = append(rows, row)
+		} else {
+			rows = append(rows, object)
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{"torrents": rows})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func benchmarkDecode(b *testing.B, table bool) {
+	data := benchmarkTorrents(b, 1000, table)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var p getResponsePayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			b.Fatal(err)
+		}
+		if len(p.Torrents) != 1000 || len(p.decodeErrs) > 0 || len(p.Torrents[0].SkippedFields()) > 0 {
+			b.Fatalf("decoded %d torrents, errors %v, skipped %v", len(p.Torrents), p.decodeErrs, p.Torrents[0].SkippedFields())
+		}
+	}
+	// Reported after the loop, as ResetTimer drops extra metrics.
+	b.ReportMetric(float64(len(data)), "payload-bytes")
+}
+
+// BenchmarkDecodeObject and BenchmarkDecodeTable compare the formats for
+// 1,000 torrents, see SetTableFormat.
+func BenchmarkDecodeObject(b *testing.B) {
+	benchmarkDecode(b, false)
+}
+
+func BenchmarkDecodeTable(b *testing.B) {
+	benchmarkDecode(b, true)
+}
+
+// cannedServer is a daemon answering every RPC with arguments, after the
+// session id handshake.
+func cannedServer(arguments string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(csrfSessionHeader) != "canned" {
+			w.Header().Set(csrfSessionHeader, "canned")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var req struct {
+			Tag int `json:"tag"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"result":"success","tag":%d,"arguments":%s}`, req.Tag, arguments)
+	}))
+}
+
+func TestStrictDecoding(t *testing.T) {
+	fields := []string{FieldId, FieldName, FieldStatus}
+	for _, tc := range []struct {
+		name      string
+		arguments string
+		wantErr   string
+	}{
+		{
+			name:      "complete",
+			arguments: `{"torrents":[{"id":1,"name":"a","status":0}]}`,
+		},
+		{
+			name:      "unknown torrent field",
+			arguments: `{"torrents":[{"id":1,"name":"a","status":0,"bogusField":3}]}`,
+			wantErr:   `torrent 0 has unknown field "bogusField"`,
+		},
+		{
+			name:      "unknown argument",
+			arguments: `{"torrents":[{"id":1,"name":"a","status":0}],"bogus-argument":true}`,
+			wantErr:   `"bogus-argument"`,
+		},
+		{
+			name:      "missing field",
+			arguments: `{"torrents":[{"id":1,"name":"a","status":0},{"id":2,"name":"b"}]}`,
+			wantErr:   `torrent 1 is missing field "status"`,
+		},
+		{
+			name:      "undecodable field",
+			arguments: `{"torrents":[{"id":1,"name":"a","status":"stopped"}]}`,
+			wantErr:   `torrent 0 has undecodable field "status"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := cannedServer(tc.arguments)
+			defer server.Close()
+
+			strict, err := NewWithOptions(server.URL, WithStrictDecoding())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = strict.GetWithFields([]int64{1, 2}, fields)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("strict GetWithFields: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("strict GetWithFields error %v, want it to contain %s", err, tc.wantErr)
+			}
+
+			// Without strict decoding the same responses are accepted.
+			tolerant, err := NewWithOptions(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tolerant.GetWithFields([]int64{1, 2}, fields); err != nil {
+				t.Errorf("GetWithFields: %v", err)
+			}
+		})
+	}
+}
+
+func TestStrictDecodingSession(t *testing.T) {
+	server := cannedServer(`{"download-dir":"/downloads","bogus-setting":1}`)
+	defer server.Close()
+
+	strict, err := NewWithOptions(server.URL, WithStrictDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.GetSession(); err == nil || !strings.Contains(err.Error(), `"bogus-setting"`) {
+		t.Errorf("strict GetSession error %v, want it to name bogus-setting", err)
+	}
+	tolerant, err := NewWithOptions(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session, err := tolerant.GetSession(); err != nil || session.DownloadDir != "/downloads" {
+		t.Errorf("GetSession = %+v, %v", session, err)
+	}
+}
+
+// decodeTorrent decodes torrent, the JSON object of one torrent as the daemon
+// sends it, through GetWithFields in the object or the table format.
+func decodeTorrent(t *testing.T, torrent string, table bool) (*Torrent, error) {
+	t.Helper()
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(torrent), &object); err != nil {
+		t.Fatalf("invalid torrent %s: %v", torrent, err)
+	}
+	var fields []string
+	for field := range object {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	arguments := fmt.Sprintf(`{"torrents":[%s]}`, torrent)
+	if table {
+		values := make([]json.RawMessage, len(fields))
+		for i, field := range fields {
+			values[i] = object[field]
+		}
+		rows, err := json.Marshal([]interface{}{fields, values})
+		if err != nil {
+			t.Fatal(err)
+		}
+		arguments = fmt.Sprintf(`{"torrents":%s}`, rows)
+	}
+	server := cannedServer(arguments)
+	defer server.Close()
+	client, err := NewWithOptions(server.URL, WithStrictDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrents, err := client.GetWithFields([]int64{1}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("GetWithFields returned %d torrents, want 1", len(torrents))
+	}
+	return torrents[0], nil
+}
+
+func TestDecodePeersAndTrackerStats(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		torrent string
+		want    *Torrent
+	}{
+		{
+			name: "peers",
+			torrent: `{"id":1,"peers":[{"address":"203.0.113.5","clientIsChoked":false,"clientIsInterested":true,
+				"clientName":"qBittorrent 4.6.2","flagStr":"DEI","isDownloadingFrom":true,"isEncrypted":true,
+				"isIncoming":false,"isUTP":true,"isUploadingTo":false,"peerIsChoked":true,"peerIsInterested":false,
+				"port":51413,"progress":0.734,"rateToClient":152300,"rateToPeer":0}]}`,
+			want: &Torrent{Id: 1, Peers: []*Peer{{
+				Address: "203.0.113.5", ClientIsInterested: true, ClientName: "qBittorrent 4.6.2", FlagStr: "DEI",
+				IsDownloadingFrom: true, IsEncrypted: true, IsUTP: true, PeerIsChoked: true,
+				Port: 51413, Progress: 0.734, RateToClient: 152300,
+			}}},
+		},
+		{
+			name:    "no peers",
+			torrent: `{"id":1,"peers":[]}`,
+			want:    &Torrent{Id: 1, Peers: []*Peer{}},
+		},
+		{
+			name: "trackerStats",
+			torrent: `{"id":1,"trackerStats":[{"announce":"https://tracker.example.com/announce","announceState":1,
+				"downloadCount":2201,"hasAnnounced":true,"hasScraped":true,"host":"https://tracker.example.com:443",
+				"id":0,"isBackup":false,"lastAnnouncePeerCount":50,"lastAnnounceResult":"Success",
+				"lastAnnounceStartTime":1700000000,"lastAnnounceSucceeded":true,"lastAnnounceTime":1700000001,
+				"lastAnnounceTimedOut":false,"lastScrapeResult":"Could not connect to tracker",
+				"lastScrapeStartTime":1700000100,"lastScrapeSucceeded":false,"lastScrapeTime":1700000130,
+				"lastScrapeTimedOut":true,"leecherCount":-1,"nextAnnounceTime":1700001801,"nextScrapeTime":1700001900,
+				"scrape":"https://tracker.example.com/scrape","scrapeState":1,"seederCount":-1,
+				"sitename":"example","tier":0},
+				{"announce":"udp://backup.example.org:6969","id":1,"isBackup":true,"tier":1}]}`,
+			want: &Torrent{Id: 1, TrackerStats: []*TrackerStat{
+				{
+					Announce: "https://tracker.example.com/announce", AnnounceState: 1, DownloadCount: 2201,
+					HasAnnounced: true, HasScraped: true, Host: "https://tracker.example.com:443",
+					LastAnnouncePeerCount: 50, LastAnnounceResult: "Success", LastAnnounceStartTime: 1700000000,
+					LastAnnounceSucceeded: true, LastAnnounceTime: 1700000001,
+					LastScrapeResult: "Could not connect to tracker", LastScrapeStartTime: 1700000100,
+					LastScrapeTime: 1700000130, LastScrapeTimedOut: true, LeecherCount: -1,
+					NextAnnounceTime: 1700001801, NextScrapeTime: 1700001900,
+					Scrape: "https://tracker.example.com/scrape", ScrapeState: 1, SeederCount: -1, Sitename: "example",
+				},
+				{Announce: "udp://backup.example.org:6969", Id: 1, IsBackup: true, Tier: 1},
+			}},
+		},
+	} {
+		for _, table := range []bool{false, true} {
+			got, err := decodeTorrent(t, tc.torrent, table)
+			if err != nil {
+				t.Errorf("%s, table %v: %v", tc.name, table, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s, table %v: decoded %s, want %s", tc.name, table, mustJSON(got), mustJSON(tc.want))
+			}
+		}
+	}
+
+	if _, err := decodeTorrent(t, `{"id":1,"peers":[{"port":"51413"}]}`, false); err == nil {
+		t.Error("decoded a peer with a string port")
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func TestDecodeWebseedsAndWanted(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		torrent string
+		want    *Torrent
+	}{
+		{
+			name:    "webseeds",
+			torrent: `{"id":1,"webseeds":["https://mirror.example.com/files/","http://mirror.example.org/a.iso"],"webseedsSendingToUs":1}`,
+			want: &Torrent{Id: 1, Webseeds: []string{"https://mirror.example.com/files/", "http://mirror.example.org/a.iso"},
+				WebseedsSendingToUs: 1},
+		},
+		{
+			name:    "no webseeds",
+			torrent: `{"id":1,"webseeds":[],"webseedsSendingToUs":0}`,
+			want:    &Torrent{Id: 1, Webseeds: []string{}},
+		},
+		{
+			name:    "wanted as numbers",
+			torrent: `{"id":1,"wanted":[1,0,1]}`,
+			want:    &Torrent{Id: 1, Wanted: Wanted{true, false, true}},
+		},
+		{
+			name:    "wanted as booleans",
+			torrent: `{"id":1,"wanted":[false,true]}`,
+			want:    &Torrent{Id: 1, Wanted: Wanted{false, true}},
+		},
+		{
+			name: "Transmission 4 fields",
+			torrent: `{"id":1,"file-count":3,"percentComplete":0.5,"primary-mime-type":"video/x-matroska",
+				"editDate":1700000000,"availability":[-1,-1,0,3]}`,
+			want: &Torrent{Id: 1, FileCount: 3, PercentComplete: 0.5, PrimaryMimeType: "video/x-matroska",
+				EditDate: 1700000000, Availability: []int64{-1, -1, 0, 3}},
+		},
+	} {
+		for _, table := range []bool{false, true} {
+			got, err := decodeTorrent(t, tc.torrent, table)
+			if err != nil {
+				t.Errorf("%s, table %v: %v", tc.name, table, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s, table %v: decoded %s, want %s", tc.name, table, mustJSON(got), mustJSON(tc.want))
+			}
+		}
+	}
+
+	for _, torrent := range []string{
+		`{"id":1,"webseeds":3}`,
+		`{"id":1,"wanted":["yes"]}`,
+	} {
+		if _, err := decodeTorrent(t, torrent, false); err == nil {
+			t.Errorf("decoded %s", torrent)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package transmission_go_api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// DialContextFunc opens the connections to the daemon, see WithDialContext.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialContext makes the client open its connections with dial instead
+// of dialing the address's host. It cannot be combined with WithHTTPClient.
+func WithDialContext(dial DialContextFunc) Option {
+	return func(c *config) error {
+		if dial == nil {
+			return fmt.Errorf("nil dial function")
+		}
+		c.dialContext = dial
+		return nil
+	}
+}
+
+// WithUnixSocket makes the client connect to the daemon through the unix
+// socket at path. The address given to NewWithOptions may then be empty, or
+// any host name, which is only sent in the Host header.
+func WithUnixSocket(path string) Option {
+	return func(c *config) error {
+		if path == "" {
+			return fmt.Errorf("empty unix socket path")
+		}
+		c.unixSocket = path
+		c.dialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		return nil
+	}
+}
+
+// transport returns a transport like http.DefaultTransport with the TLS and
+// dialer options applied.
+func (c *config) transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.tlsConfig != nil {
+		transport.TLSClientConfig = c.tlsConfig
+	}
+	if c.dialContext != nil {
+		transport.DialContext = c.dialContext
+	}
+	return transport
+}
@@ -0,0 +1,104 @@
+package transmission_go_api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AuthError is returned when the daemon rejects the client with 401
+// Unauthorized or 403 Forbidden. A 403 AuthError matches ErrForbidden.
+type AuthError struct {
+	StatusCode int
+	Username   string // The username the client sent, if any.
+	// Message is the text of the daemon's HTML error page, like "Unauthorized
+	// IP Address." for a client outside the rpc-whitelist.
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	if e.StatusCode == 403 {
+		switch {
+		case strings.Contains(e.Message, "whitelist"):
+			return "403 Forbidden: the client address is not in the daemon's rpc-whitelist"
+		case strings.Contains(e.Message, "unsuccessful login attempts"):
+			return "403 Forbidden: anti-brute-force banned the client after repeated failed logins, restart the daemon to lift the ban"
+		}
+		return ErrForbidden.Error()
+	}
+	if e.Username == "" {
+		return "401 Unauthorized: the daemon requires a username and password"
+	}
+	return fmt.Sprintf("401 Unauthorized: the daemon rejected username %q or its password", e.Username)
+}
+
+func (e *AuthError) Is(target error) bool {
+	return target == ErrForbidden && e.StatusCode == 403
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// htmlText returns the text of an HTML error page, without tags.
+func htmlText(body []byte) string {
+	text := htmlTag.ReplaceAllString(string(body), " ")
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// ConnError is returned when the daemon could not be reached, or the
+// connection broke before the response was read.
+type ConnError struct {
+	Err error
+}
+
+func (e *ConnError) Error() string {
+	return "connecting to transmission: " + e.Err.Error()
+}
+
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPError is returned for HTTP responses other than 200 that have no more
+// specific error, like a 502 from a reverse proxy.
+type HTTPError struct {
+	Method     string
+	StatusCode int
+	Status     string
+	// Body holds the start of the response body.
+	Body string
+}
+
+// maxErrorBody limits how much of the response body an HTTPError keeps.
+const maxErrorBody = 512
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s: unexpected HTTP status %s", e.Method, e.Status)
+	}
+	return fmt.Sprintf("%s: unexpected HTTP status %s: %s", e.Method, e.Status, e.Body)
+}
+
+// RPCError is returned when the daemon answers with a result other than
+// "success", for example because it rejected the arguments.
+type RPCError struct {
+	Method string
+	Result string
+	Tag    int
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (tag %d): %s", e.Method, e.Tag, e.Result)
+}
+
+// SessionError is returned when the daemon asks for a new session id with a
+// 409 response, but does not hand out a usable one.
+type SessionError struct {
+	Reason string
+}
+
+func (e *SessionError) Error() string {
+	return "transmission session: " + e.Reason
+}
@@ -0,0 +1,162 @@
+package transmission_go_api_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+// Error pages as transmission-daemon sends them.
+const (
+	unauthorizedPage = "<h1>401: Unauthorized</h1>Unauthorized User"
+	whitelistPage    = "<h1>403: Forbidden</h1>" +
+		"<p>Unauthorized IP Address.</p>" +
+		"<p>Either disable the IP address whitelist or add your address to it.</p>" +
+		"<p>If you're editing settings.json, see the 'rpc-whitelist' and 'rpc-whitelist-enabled' entries.</p>" +
+		"<p>If you're still using ACLs, use a whitelist instead. See the transmission-daemon manpage for details.</p>"
+	bruteForcePage = "<h1>403: Forbidden</h1>" +
+		"<p>Too many unsuccessful login attempts. Please restart transmission-daemon.</p>"
+)
+
+func TestAuthErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		status      int
+		page        string
+		opts        []transmission.Option
+		wantMessage string
+		wantError   string
+		forbidden   bool
+	}{
+		{
+			name:        "no credentials",
+			status:      http.StatusUnauthorized,
+			page:        unauthorizedPage,
+			wantMessage: "401: Unauthorized Unauthorized User",
+			wantError:   "the daemon requires a username and password",
+		},
+		{
+			name:        "wrong credentials",
+			status:      http.StatusUnauthorized,
+			page:        unauthorizedPage,
+			opts:        []transmission.Option{transmission.WithBasicAuth("bob", "wrong")},
+			wantMessage: "401: Unauthorized Unauthorized User",
+			wantError:   `rejected username "bob"`,
+		},
+		{
+			name:        "whitelist",
+			status:      http.StatusForbidden,
+			page:        whitelistPage,
+			wantMessage: "403: Forbidden Unauthorized IP Address.",
+			wantError:   "not in the daemon's rpc-whitelist",
+			forbidden:   true,
+		},
+		{
+			name:        "anti-brute-force",
+			status:      http.StatusForbidden,
+			page:        bruteForcePage,
+			wantMessage: "403: Forbidden Too many unsuccessful login attempts. Please restart transmission-daemon.",
+			wantError:   "anti-brute-force banned the client",
+			forbidden:   true,
+		},
+		{
+			name:        "other 403",
+			status:      http.StatusForbidden,
+			page:        "<html><body>Access denied by proxy</body></html>",
+			wantMessage: "Access denied by proxy",
+			wantError:   transmission.ErrForbidden.Error(),
+			forbidden:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := transmissiontest.NewServer()
+			defer s.Close()
+			client := newClient(t, s.URL, tc.opts...)
+			s.FailNext(tc.status, tc.page)
+
+			_, err := client.GetSession()
+			var authErr *transmission.AuthError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("GetSession = %v, want AuthError", err)
+			}
+			if authErr.StatusCode != tc.status {
+				t.Errorf("StatusCode = %d, want %d", authErr.StatusCode, tc.status)
+			}
+			if !strings.HasPrefix(authErr.Message, tc.wantMessage) {
+				t.Errorf("Message = %q, want it to start with %q", authErr.Message, tc.wantMessage)
+			}
+			if !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("error %q, want it to contain %q", err, tc.wantError)
+			}
+			if got := errors.Is(err, transmission.ErrForbidden); got != tc.forbidden {
+				t.Errorf("errors.Is(err, ErrForbidden) = %v, want %v", got, tc.forbidden)
+			}
+		})
+	}
+}
+
+// countingDialer counts the connections a client opens.
+type countingDialer struct {
+	dials int64
+}
+
+func (d *countingDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	atomic.AddInt64(&d.dials, 1)
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func (d *countingDialer) count() int64 {
+	return atomic.LoadInt64(&d.dials)
+}
+
+func TestHTTPErrors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	dialer := &countingDialer{}
+	client := newClient(t, s.URL, transmission.WithDialContext(dialer.dial))
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+
+	longBody := strings.Repeat("x", 20<<10)
+	for _, tc := range []struct {
+		status   int
+		body     string
+		wantBody string
+	}{
+		{http.StatusInternalServerError, longBody, longBody[:512]},
+		{http.StatusBadGateway, "<html><h1>502 Bad Gateway</h1></html>", "<html><h1>502 Bad Gateway</h1></html>"},
+		{http.StatusNoContent, "", ""},
+	} {
+		s.FailNext(tc.status, tc.body)
+		_, err := client.GetSession()
+		var httpErr *transmission.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Errorf("%d: GetSession = %v, want HTTPError", tc.status, err)
+			continue
+		}
+		if httpErr.Method != "session-get" || httpErr.StatusCode != tc.status || httpErr.Body != tc.wantBody {
+			t.Errorf("%d: got HTTPError{%q, %d, body of %d bytes}, want {session-get, %d, body of %d bytes}",
+				tc.status, httpErr.Method, httpErr.StatusCode, len(httpErr.Body), tc.status, len(tc.wantBody))
+		}
+		if !strings.Contains(err.Error(), "session-get") || !strings.Contains(err.Error(), http.StatusText(tc.status)) {
+			t.Errorf("%d: error %q should name the method and status", tc.status, err)
+		}
+	}
+
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession after the errors: %v", err)
+	}
+	// The error bodies were drained, so one connection served everything.
+	if got := dialer.count(); got != 1 {
+		t.Errorf("client opened %d connections, want 1", got)
+	}
+}
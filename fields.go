@@ -0,0 +1,299 @@
+package transmission_go_api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Torrent field names accepted by torrent-get.
+const (
+	FieldActivityDate            = "activityDate"
+	FieldAddedDate               = "addedDate"
+	FieldAvailability            = "availability"
+	FieldBandwidthPriority       = "bandwidthPriority"
+	FieldComment                 = "comment"
+	FieldCorruptEver             = "corruptEver"
+	FieldCreator                 = "creator"
+	FieldDateCreated             = "dateCreated"
+	FieldDesiredAvailable        = "desiredAvailable"
+	FieldDoneDate                = "doneDate"
+	FieldDownloadDir             = "downloadDir"
+	FieldDownloadedEver          = "downloadedEver"
+	FieldDownloadLimit           = "downloadLimit"
+	FieldDownloadLimited         = "downloadLimited"
+	FieldEditDate                = "editDate"
+	FieldError                   = "error"
+	FieldErrorString             = "errorString"
+	FieldEta                     = "eta"
+	FieldEtaIdle                 = "etaIdle"
+	FieldFileCount               = "file-count"
+	FieldFiles                   = "files"
+	FieldFileStats               = "fileStats"
+	FieldGroup                   = "group"
+	FieldHashString              = "hashString"
+	FieldHaveUnchecked           = "haveUnchecked"
+	FieldHaveValid               = "haveValid"
+	FieldHonorsSessionLimits     = "honorsSessionLimits"
+	FieldId                      = "id"
+	FieldIsFinished              = "isFinished"
+	FieldIsPrivate               = "isPrivate"
+	FieldIsStalled               = "isStalled"
+	FieldLabels                  = "labels"
+	FieldLeftUntilDone           = "leftUntilDone"
+	FieldMagnetLink              = "magnetLink"
+	FieldManualAnnounceTime      = "manualAnnounceTime"
+	FieldMaxConnectedPeers       = "maxConnectedPeers"
+	FieldMetadataPercentComplete = "metadataPercentComplete"
+	FieldName                    = "name"
+	FieldPeerLimit               = "peer-limit"
+	FieldPeers                   = "peers"
+	FieldPeersConnected          = "peersConnected"
+	FieldPeersFrom               = "peersFrom"
+	FieldPeersGettingFromUs      = "peersGettingFromUs"
+	FieldPeersSendingToUs        = "peersSendingToUs"
+	FieldPercentComplete         = "percentComplete"
+	FieldPercentDone             = "percentDone"
+	FieldPieces                  = "pieces"
+	FieldPieceCount              = "pieceCount"
+	FieldPieceSize               = "pieceSize"
+	FieldPriorities              = "priorities"
+	FieldPrimaryMimeType         = "primary-mime-type"
+	FieldQueuePosition           = "queuePosition"
+	FieldRateDownload            = "rateDownload"
+	FieldRateUpload              = "rateUpload"
+	FieldRecheckProgress         = "recheckProgress"
+	FieldSecondsDownloading      = "secondsDownloading"
+	FieldSecondsSeeding          = "secondsSeeding"
+	FieldSeedIdleLimit           = "seedIdleLimit"
+	FieldSeedIdleMode            = "seedIdleMode"
+	FieldSeedRatioLimit          = "seedRatioLimit"
+	FieldSeedRatioMode           = "seedRatioMode"
+	FieldSequentialDownload      = "sequentialDownload"
+	FieldSizeWhenDone            = "sizeWhenDone"
+	FieldStartDate               = "startDate"
+	FieldStatus                  = "status"
+	FieldTrackers                = "trackers"
+	FieldTrackerList             = "trackerList"
+	FieldTrackerStats            = "trackerStats"
+	FieldTotalSize               = "totalSize"
+	FieldTorrentFile             = "torrentFile"
+	FieldUploadedEver            = "uploadedEver"
+	FieldUploadLimit             = "uploadLimit"
+	FieldUploadLimited           = "uploadLimited"
+	FieldUploadRatio             = "uploadRatio"
+	FieldWanted                  = "wanted"
+	FieldWebseeds                = "webseeds"
+	FieldWebseedsSendingToUs     = "webseedsSendingToUs"
+)
+
+type fieldInfo struct {
+	name       string
+	rpcVersion int64 // First RPC version returning the field.
+	listed     bool  // Requested by ListAll.
+}
+
+// fieldTable describes every Field* constant.
+var fieldTable = []fieldInfo{
+	{FieldActivityDate, 0, true},
+	{FieldAddedDate, 0, true},
+	{FieldAvailability, 18, true},
+	{FieldBandwidthPriority, 0, true},
+	{FieldComment, 0, true},
+	{FieldCorruptEver, 0, true},
+	{FieldCreator, 0, true},
+	{FieldDateCreated, 0, true},
+	{FieldDesiredAvailable, 0, true},
+	{FieldDoneDate, 0, true},
+	{FieldDownloadDir, 0, true},
+	{FieldDownloadedEver, 0, true},
+	{FieldDownloadLimit, 0, true},
+	{FieldDownloadLimited, 0, true},
+	{FieldEditDate, 16, true},
+	{FieldError, 0, true},
+	{FieldErrorString, 0, true},
+	{FieldEta, 0, true},
+	{FieldEtaIdle, 0, true},
+	{FieldFileCount, 17, true},
+	{FieldFiles, 0, true},
+	{FieldFileStats, 0, true},
+	{FieldGroup, 17, true},
+	{FieldHashString, 0, true},
+	{FieldHaveUnchecked, 0, true},
+	{FieldHaveValid, 0, true},
+	{FieldHonorsSessionLimits, 0, true},
+	{FieldId, 0, true},
+	{FieldIsFinished, 0, true},
+	{FieldIsPrivate, 0, true},
+	{FieldIsStalled, 0, true},
+	{FieldLabels, 16, true},
+	{FieldLeftUntilDone, 0, true},
+	{FieldMagnetLink, 0, true},
+	{FieldManualAnnounceTime, 0, true},
+	{FieldMaxConnectedPeers, 0, true},
+	{FieldMetadataPercentComplete, 0, true},
+	{FieldName, 0, true},
+	{FieldPeerLimit, 0, true},
+	{FieldPeers, 0, false},
+	{FieldPeersConnected, 0, false},
+	{FieldPeersFrom, 0, false},
+	{FieldPeersGettingFromUs, 0, false},
+	{FieldPeersSendingToUs, 0, false},
+	{FieldPercentComplete, 17, true},
+	{FieldPercentDone, 0, true},
+	{FieldPieces, 0, true},
+	{FieldPieceCount, 0, true},
+	{FieldPieceSize, 0, true},
+	{FieldPriorities, 0, true},
+	{FieldPrimaryMimeType, 17, true},
+	{FieldQueuePosition, 0, true},
+	{FieldRateDownload, 0, true},
+	{FieldRateUpload, 0, true},
+	{FieldRecheckProgress, 0, true},
+	{FieldSecondsDownloading, 0, true},
+	{FieldSecondsSeeding, 0, true},
+	{FieldSeedIdleLimit, 0, true},
+	{FieldSeedIdleMode, 0, true},
+	{FieldSeedRatioLimit, 0, true},
+	{FieldSeedRatioMode, 0, true},
+	{FieldSequentialDownload, 18, true},
+	{FieldSizeWhenDone, 0, true},
+	{FieldStartDate, 0, true},
+	{FieldStatus, 0, true},
+	{FieldTrackers, 0, false},
+	{FieldTrackerList, 17, true},
+	{FieldTrackerStats, 0, true},
+	{FieldTotalSize, 0, true},
+	{FieldTorrentFile, 0, true},
+	{FieldUploadedEver, 0, true},
+	{FieldUploadLimit, 0, true},
+	{FieldUploadLimited, 0, true},
+	{FieldUploadRatio, 0, true},
+	{FieldWanted, 0, true},
+	{FieldWebseeds, 0, true},
+	{FieldWebseedsSendingToUs, 0, true},
+}
+
+// FieldSet is a list of torrent field names, see the Field* constants.
+type FieldSet []string
+
+// AllFields returns every known torrent field.
+func AllFields() FieldSet {
+	fields := make(FieldSet, 0, len(fieldTable))
+	for _, info := range fieldTable {
+		fields = append(fields, info.name)
+	}
+	return fields
+}
+
+// BriefFields returns the cheap fields a status overview needs, as fetched by
+// ListBrief.
+func BriefFields() FieldSet {
+	return FieldSet{
+		FieldId,
+		FieldName,
+		FieldHashString,
+		FieldStatus,
+		FieldPercentDone,
+		FieldRateDownload,
+		FieldRateUpload,
+		FieldEta,
+		FieldTotalSize,
+		FieldErrorString,
+		FieldAddedDate,
+	}
+}
+
+// listedFields returns the fields ListAll requests from a daemon with the
+// given RPC version.
+func listedFields(version int64) FieldSet {
+	var fields FieldSet
+	for _, info := range fieldTable {
+		if info.listed && info.rpcVersion <= version {
+			fields = append(fields, info.name)
+		}
+	}
+	return fields
+}
+
+// Validate returns an error for the first unknown field name in s, with a
+// suggestion when the name looks like a typo of a known one.
+func (s FieldSet) Validate() error {
+	if len(s) == 0 {
+		return fmt.Errorf("no torrent fields given")
+	}
+	for _, field := range s {
+		known := false
+		for _, info := range fieldTable {
+			if field == info.name {
+				known = true
+				break
+			}
+		}
+		if known {
+			continue
+		}
+		if suggestion := closestField(field); suggestion != "" {
+			return fmt.Errorf("unknown torrent field %q, did you mean %q?", field, suggestion)
+		}
+		return fmt.Errorf("unknown torrent field %q, valid fields are: %s", field, strings.Join(AllFields(), ", "))
+	}
+	return nil
+}
+
+// closestField returns the known field nearest to field, or "" if none is
+// close enough to be a likely typo.
+func closestField(field string) string {
+	best, bestDist := "", 4
+	for _, info := range fieldTable {
+		d := editDistance(strings.ToLower(field), strings.ToLower(info.name))
+		if d < bestDist {
+			best, bestDist = info.name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// heavyFields dominate the size of a torrent-get response.
+var heavyFields = map[string]bool{
+	FieldFiles:     true,
+	FieldFileStats: true,
+	FieldPieces:    true,
+}
+
+// lightFields returns listedFields without the heavy fields.
+func lightFields(version int64) FieldSet {
+	var fields FieldSet
+	for _, name := range listedFields(version) {
+		if !heavyFields[name] {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,16 @@
+package transmission_go_api
+
+// Logger receives the client's log messages, see WithLogger. Debug messages
+// include the JSON of every request and response.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// nopLogger is the default Logger, which drops everything.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Warnf(format string, args ...interface{})  {}
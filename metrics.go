@@ -0,0 +1,103 @@
+package transmission_go_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrorClass sorts the errors of RPCs into a few kinds for metrics.
+type ErrorClass string
+
+const (
+	ErrorClassNone       ErrorClass = ""           // The RPC succeeded.
+	ErrorClassTimeout    ErrorClass = "timeout"    // ErrTimeout or a context deadline.
+	ErrorClassCanceled   ErrorClass = "canceled"   // The context was canceled.
+	ErrorClassConnection ErrorClass = "connection" // A ConnError.
+	ErrorClassAuth       ErrorClass = "auth"       // An AuthError.
+	ErrorClassSession    ErrorClass = "session"    // A SessionError.
+	ErrorClassHTTP       ErrorClass = "http"       // An HTTPError.
+	ErrorClassRPC        ErrorClass = "rpc"        // An RPCError.
+	ErrorClassDecode     ErrorClass = "decode"     // The response was not valid JSON for the call.
+	ErrorClassOther      ErrorClass = "other"
+)
+
+// RequestMetrics describes one RPC, see WithMetrics.
+type RequestMetrics struct {
+	Method string
+	// StatusCode is the HTTP status of the last response, or 0 if none was
+	// received.
+	StatusCode int
+	// Duration includes waiting for the rate limit, the 409 retry and the
+	// retries of WithRetry.
+	Duration time.Duration
+	// RequestBytes and ResponseBytes are the sizes of the last request and
+	// response JSON, before compression.
+	RequestBytes  int64
+	ResponseBytes int64
+	// Retries counts the attempts after the first made by WithRetry. The 409
+	// retry is not counted.
+	Retries int
+	Err     error
+	Class   ErrorClass
+}
+
+// WithMetrics makes the client call record once after each RPC, successful or
+// not, for example to feed Prometheus or statsd. record is called on the
+// goroutine that made the call and should not block. A nil record disables
+// it.
+func WithMetrics(record func(m RequestMetrics)) Option {
+	return func(c *config) error {
+		c.metrics = record
+		return nil
+	}
+}
+
+// classifyError returns the ErrorClass of an error returned by doRPC.
+func classifyError(err error) ErrorClass {
+	var (
+		authErr    *AuthError
+		connErr    *ConnError
+		httpErr    *HTTPError
+		rpcErr     *RPCError
+		sessionErr *SessionError
+		syntaxErr  *json.SyntaxError
+		typeErr    *json.UnmarshalTypeError
+	)
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &authErr):
+		return ErrorClassAuth
+	case errors.As(err, &sessionErr):
+		return ErrorClassSession
+	case errors.As(err, &httpErr):
+		return ErrorClassHTTP
+	case errors.As(err, &rpcErr):
+		return ErrorClassRPC
+	case errors.As(err, &connErr):
+		return ErrorClassConnection
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, ErrTagMismatch):
+		return ErrorClassDecode
+	}
+	return ErrorClassOther
+}
+
+// recordMetrics calls the metrics hook for an RPC that started at start.
+func (t *Transmission) recordMetrics(method string, start time.Time, trace *rpcTrace, err error) {
+	t.config.metrics(RequestMetrics{
+		Method:        method,
+		StatusCode:    trace.statusCode,
+		Duration:      time.Since(start),
+		RequestBytes:  trace.requestBytes,
+		ResponseBytes: trace.responseBytes,
+		Retries:       trace.retries,
+		Err:           err,
+		Class:         classifyError(err),
+	})
+}
@@ -0,0 +1,85 @@
+package transmission_go_api_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+func TestMetrics(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	id := s.AddTorrent(transmission.Torrent{Name: "a"})
+	s.SetLatency(10 * time.Millisecond)
+	var mu sync.Mutex
+	var records []transmission.RequestMetrics
+	client := newClient(t, s.URL,
+		transmission.WithRetry(2, time.Millisecond),
+		transmission.WithMetrics(func(m transmission.RequestMetrics) {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, m)
+		}),
+	)
+
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if _, err := client.GetWithFields([]int64{id}, []string{transmission.FieldName}); err != nil {
+		t.Fatalf("GetWithFields: %v", err)
+	}
+	s.FailNext(http.StatusServiceUnavailable, "")
+	if err := client.Start([]int64{id}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	s.SetResult("torrent-stop", "torrent is busy")
+	if err := client.Stop([]int64{id}); err == nil {
+		t.Fatal("Stop succeeded")
+	}
+	s.FailNext(http.StatusInternalServerError, "")
+	if _, err := client.GetSession(); err == nil {
+		t.Fatal("GetSession succeeded")
+	}
+
+	want := []struct {
+		method     string
+		statusCode int
+		retries    int
+		class      transmission.ErrorClass
+	}{
+		{"session-get", http.StatusOK, 0, transmission.ErrorClassNone},
+		{"torrent-get", http.StatusOK, 0, transmission.ErrorClassNone},
+		{"torrent-start", http.StatusOK, 1, transmission.ErrorClassNone},
+		{"torrent-stop", http.StatusOK, 0, transmission.ErrorClassRPC},
+		{"session-get", http.StatusInternalServerError, 0, transmission.ErrorClassHTTP},
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != len(want) {
+		t.Fatalf("got %d metrics records, want %d: %+v", len(records), len(want), records)
+	}
+	for i, w := range want {
+		m := records[i]
+		if m.Method != w.method || m.StatusCode != w.statusCode || m.Retries != w.retries || m.Class != w.class {
+			t.Errorf("record %d = %s %d, %d retries, class %q; want %s %d, %d retries, class %q",
+				i, m.Method, m.StatusCode, m.Retries, m.Class, w.method, w.statusCode, w.retries, w.class)
+		}
+		if (m.Err != nil) != (w.class != transmission.ErrorClassNone) {
+			t.Errorf("record %d has error %v, want class %q", i, m.Err, w.class)
+		}
+		// Every call waits for the latency at least once.
+		if m.Duration < 10*time.Millisecond {
+			t.Errorf("record %d has duration %v, want at least 10ms", i, m.Duration)
+		}
+		if m.RequestBytes == 0 {
+			t.Errorf("record %d has no request bytes", i)
+		}
+	}
+	if records[1].ResponseBytes == 0 {
+		t.Error("torrent-get record has no response bytes")
+	}
+}
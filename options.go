@@ -0,0 +1,288 @@
+package transmission_go_api
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+// Option configures a Transmission client, see NewWithOptions. Options
+// return an error for invalid settings, which NewWithOptions returns.
+type Option func(*config) error
+
+// config holds the settings made by the options.
+type config struct {
+	username string
+	password string
+
+	// httpClient is nil unless WithHTTPClient was given.
+	httpClient *http.Client
+	// userAgent overrides DefaultUserAgent, see WithUserAgent.
+	userAgent string
+	// cookieJar is nil unless WithCookieJar or WithCookies was given.
+	cookieJar http.CookieJar
+	// rpcPath overrides defaultRPCPath, see WithRPCPath.
+	rpcPath string
+	// tlsConfig is nil unless a TLS option was given.
+	tlsConfig *tls.Config
+	// dialContext is nil unless WithDialContext or WithUnixSocket was given.
+	dialContext DialContextFunc
+	unixSocket  string
+
+	// retry is nil unless WithRetry was given.
+	retry *retryPolicy
+	// rateLimit is nil unless WithRateLimit was given.
+	rateLimit *rateLimiter
+
+	// timeout bounds every RPC, including the 409 retry. 0 means no bound.
+	timeout time.Duration
+
+	// sessionId is the session id to start with, see WithSessionId.
+	sessionId string
+	// sessionIdChanged is nil unless WithSessionIdChanged was given.
+	sessionIdChanged func(string)
+
+	// strictDecoding rejects responses with unknown or missing fields.
+	strictDecoding bool
+
+	logger Logger
+
+	// debugHook is nil unless WithDebugHook was given.
+	debugHook DebugHook
+	// metrics is nil unless WithMetrics was given.
+	metrics func(RequestMetrics)
+
+	requestInterceptors  []func(*http.Request) error
+	responseInterceptors []func(*http.Response) error
+}
+
+// WithBasicAuth logs in with the daemon's rpc-username and rpc-password.
+func WithBasicAuth(username, password string) Option {
+	return func(c *config) error {
+		if username == "" {
+			return fmt.Errorf("empty username")
+		}
+		c.username = username
+		c.password = password
+		return nil
+	}
+}
+
+// WithHTTPClient makes the client send its requests with httpClient, for
+// example one with a custom transport. It cannot be combined with the TLS,
+// dialer and cookie options.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *config) error {
+		if httpClient == nil {
+			return fmt.Errorf("nil HTTP client")
+		}
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithCookieJar makes the client keep the cookies the server sets in jar and
+// send them back with every request, as needed behind single sign-on proxies
+// that set a session cookie after the first request. It cannot be combined
+// with WithHTTPClient, set the Jar of that client instead.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *config) error {
+		if jar == nil {
+			return fmt.Errorf("nil cookie jar")
+		}
+		c.cookieJar = jar
+		return nil
+	}
+}
+
+// WithCookies is like WithCookieJar with an in-memory jar, so the cookies
+// last as long as the client.
+func WithCookies() Option {
+	return func(c *config) error {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return err
+		}
+		c.cookieJar = jar
+		return nil
+	}
+}
+
+// WithSessionId makes the client start with a session id saved from an
+// earlier client, see Transmission.SessionId. A stale id costs one 409 round
+// trip, as if none was given.
+func WithSessionId(id string) Option {
+	return func(c *config) error {
+		c.sessionId = id
+		return nil
+	}
+}
+
+// WithSessionIdChanged makes the client call changed with the new session id
+// whenever the daemon hands one out, for example to save it for the next run.
+// It is called on the goroutine of the RPC, which waits for it.
+func WithSessionIdChanged(changed func(id string)) Option {
+	return func(c *config) error {
+		c.sessionIdChanged = changed
+		return nil
+	}
+}
+
+// WithStrictDecoding makes the client fail on responses with fields it does
+// not know, on torrent fields it cannot decode, and on torrents missing a
+// requested field. It is meant for catching changes in the daemon's
+// responses during development.
+func WithStrictDecoding() Option {
+	return func(c *config) error {
+		c.strictDecoding = true
+		return nil
+	}
+}
+
+// WithTimeout bounds every RPC to d, including the retry after the daemon
+// hands out a new session id. A call that times out returns ErrTimeout. Use
+// WithCallTimeout to change it for single calls.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		if d < 0 {
+			return fmt.Errorf("invalid timeout %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+type callTimeoutKey struct{}
+
+// WithCallTimeout returns a context overriding the client's timeout for the
+// calls made with it, for slow calls like UpdateBlocklistContext or adding a
+// large torrent. A d of 0 disables the timeout.
+func WithCallTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, d)
+}
+
+// WithRetry retries RPCs that fail with a refused or reset connection, an
+// early EOF, or a 502, 503 or 504 from a reverse proxy, up to maxAttempts
+// attempts in total. The delay starts around baseDelay and doubles with each
+// retry. Authentication and RPC failures are never retried, nor are calls
+// like torrent-add that are not safe to repeat, see WithRetryNonIdempotent.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *config) error {
+		if maxAttempts < 1 {
+			return fmt.Errorf("invalid retry attempts %d", maxAttempts)
+		}
+		if baseDelay < 0 {
+			return fmt.Errorf("invalid retry delay %v", baseDelay)
+		}
+		if c.retry == nil {
+			c.retry = &retryPolicy{}
+		}
+		c.retry.maxAttempts = maxAttempts
+		c.retry.baseDelay = baseDelay
+		return nil
+	}
+}
+
+// WithRetryNonIdempotent lets WithRetry also retry torrent-add,
+// torrent-rename-path, queue-move-up, queue-move-down and session-close.
+// A retried call may then take effect twice.
+func WithRetryNonIdempotent() Option {
+	return func(c *config) error {
+		if c.retry == nil {
+			c.retry = &retryPolicy{maxAttempts: 1}
+		}
+		c.retry.nonIdempotent = true
+		return nil
+	}
+}
+
+// WithRPCPath sets the path the daemon serves RPC at, instead of
+// /transmission/rpc, as when it is behind a reverse proxy. It is only used
+// for addresses without a path.
+func WithRPCPath(path string) Option {
+	return func(c *config) error {
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			return fmt.Errorf("empty RPC path")
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		c.rpcPath = path
+		return nil
+	}
+}
+
+// WithLogger makes the client log to logger. By default it does not log.
+// Credentials are never logged.
+func WithLogger(logger Logger) Option {
+	return func(c *config) error {
+		if logger == nil {
+			return fmt.Errorf("nil logger")
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// DebugHook receives the JSON of an RPC's request and response, and the
+// error the call returns. The response is nil if none was read.
+type DebugHook func(method string, requestJSON, responseJSON []byte, err error)
+
+// WithDebugHook makes the client call hook once after each RPC, with copies
+// of the raw JSON sent and received, for example to dump the traffic while
+// reproducing a daemon bug. The credentials are sent in headers and are not
+// part of the JSON. A nil hook disables it.
+func WithDebugHook(hook DebugHook) Option {
+	return func(c *config) error {
+		c.debugHook = hook
+		return nil
+	}
+}
+
+// rpcTrace collects what the debug and metrics hooks report about an RPC.
+type rpcTrace struct {
+	// keepJSON is set for the debug hook, which gets request and response.
+	keepJSON bool
+	request  []byte
+	response []byte
+
+	statusCode    int
+	requestBytes  int64
+	responseBytes int64
+	retries       int
+}
+
+type rpcTraceKey struct{}
+
+// WithRequestInterceptor makes the client pass every HTTP request to
+// intercept before sending it, for example to add a header for a reverse
+// proxy. Interceptors run in the order they were given, for every attempt,
+// including the retry with a new session id. An error aborts the call.
+func WithRequestInterceptor(intercept func(*http.Request) error) Option {
+	return func(c *config) error {
+		if intercept == nil {
+			return fmt.Errorf("nil request interceptor")
+		}
+		c.requestInterceptors = append(c.requestInterceptors, intercept)
+		return nil
+	}
+}
+
+// WithResponseInterceptor makes the client pass every HTTP response to
+// intercept before handling it, including the 409 responses handing out a
+// session id. Interceptors run in the order they were given. An error aborts
+// the call.
+func WithResponseInterceptor(intercept func(*http.Response) error) Option {
+	return func(c *config) error {
+		if intercept == nil {
+			return fmt.Errorf("nil response interceptor")
+		}
+		c.responseInterceptors = append(c.responseInterceptors, intercept)
+		return nil
+	}
+}
@@ -0,0 +1,315 @@
+package transmission_go_api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+// pathServer answers session-get on any path and records the paths asked.
+type pathServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func newPathServer() *pathServer {
+	s := &pathServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.paths = append(s.paths, r.URL.Path)
+		s.mu.Unlock()
+		var req struct {
+			Tag int `json:"tag"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"result":"success","arguments":{},"tag":%d}`, req.Tag)
+	}))
+	return s
+}
+
+func (s *pathServer) lastPath() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.paths) == 0 {
+		return ""
+	}
+	return s.paths[len(s.paths)-1]
+}
+
+func TestRPCPath(t *testing.T) {
+	s := newPathServer()
+	defer s.Close()
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	for _, tc := range []struct {
+		address string
+		opts    []transmission.Option
+		want    string
+	}{
+		{host, nil, "/transmission/rpc"},
+		{s.URL + "/", nil, "/transmission/rpc"},
+		{host, []transmission.Option{transmission.WithRPCPath("/proxy/rpc")}, "/proxy/rpc"},
+		{host + "/", []transmission.Option{transmission.WithRPCPath("proxy/rpc/")}, "/proxy/rpc"},
+		{s.URL + "/transmission/rpc", []transmission.Option{transmission.WithRPCPath("/other")}, "/transmission/rpc"},
+		{s.URL + "/tr/rpc/", []transmission.Option{transmission.WithRPCPath("/other")}, "/tr/rpc"},
+		{host + "/custom", nil, "/custom"},
+	} {
+		client := newClient(t, tc.address, tc.opts...)
+		if _, err := client.GetSession(); err != nil {
+			t.Errorf("%s: GetSession: %v", tc.address, err)
+			continue
+		}
+		if got := s.lastPath(); got != tc.want {
+			t.Errorf("%s: request went to %q, want %q", tc.address, got, tc.want)
+		}
+	}
+}
+
+func TestRPCPathInvalid(t *testing.T) {
+	for _, path := range []string{"", "/", "//"} {
+		if _, err := transmission.NewWithOptions("localhost", transmission.WithRPCPath(path)); err == nil {
+			t.Errorf("WithRPCPath(%q) succeeded", path)
+		}
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+	client := newClient(t, s.URL,
+		transmission.WithRequestInterceptor(func(req *http.Request) error {
+			record("first")
+			req.Header.Set("X-Proxy-Token", "first")
+			return nil
+		}),
+		transmission.WithRequestInterceptor(func(req *http.Request) error {
+			record("second:" + req.Header.Get("X-Proxy-Token"))
+			req.Header.Set("X-Proxy-Token", "second")
+			return nil
+		}),
+		transmission.WithResponseInterceptor(func(resp *http.Response) error {
+			record(fmt.Sprintf("response:%d", resp.StatusCode))
+			return nil
+		}),
+	)
+
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	// The interceptors run again for the retry after the 409.
+	want := []string{"first", "second:first", "response:409", "first", "second:first", "response:200"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("interceptor calls %v, want %v", calls, want)
+	}
+	requests := s.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("server got %d requests, want 2", len(requests))
+	}
+	for i, req := range requests {
+		if got := req.Header.Get("X-Proxy-Token"); got != "second" {
+			t.Errorf("request %d X-Proxy-Token = %q, want second", i, got)
+		}
+	}
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	errDenied := errors.New("denied")
+
+	client := newClient(t, s.URL, transmission.WithRequestInterceptor(func(*http.Request) error {
+		return errDenied
+	}))
+	if _, err := client.GetSession(); !errors.Is(err, errDenied) {
+		t.Errorf("GetSession = %v, want the request interceptor's error", err)
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Errorf("server got %d requests, want 0", n)
+	}
+
+	client = newClient(t, s.URL, transmission.WithResponseInterceptor(func(*http.Response) error {
+		return errDenied
+	}))
+	if _, err := client.GetSession(); !errors.Is(err, errDenied) {
+		t.Errorf("GetSession = %v, want the response interceptor's error", err)
+	}
+	if n := len(s.Requests()); n != 1 {
+		t.Errorf("server got %d requests, want 1", n)
+	}
+}
+
+func TestSessionId(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	saved := client.SessionId()
+	if saved == "" {
+		t.Fatal("SessionId is empty after a call")
+	}
+
+	// A saved id that is still current spares the 409.
+	var changes []string
+	changed := transmission.WithSessionIdChanged(func(id string) { changes = append(changes, id) })
+	client = newClient(t, s.URL, transmission.WithSessionId(saved), changed)
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if n := len(s.Requests()); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+	if len(changes) != 0 {
+		t.Errorf("session id changed to %v, want no change", changes)
+	}
+
+	// A stale id goes through the 409 once.
+	s.ExpireSession()
+	client = newClient(t, s.URL, transmission.WithSessionId(saved), changed)
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetSession(); err != nil {
+			t.Fatalf("GetSession: %v", err)
+		}
+	}
+	requests := s.Requests()[3:]
+	if len(requests) != 4 {
+		t.Fatalf("server got %d requests with a stale id, want 4", len(requests))
+	}
+	if got := requests[0].Header.Get("X-Transmission-Session-Id"); got != saved {
+		t.Errorf("first request sent session id %q, want %q", got, saved)
+	}
+	current := client.SessionId()
+	if current == saved {
+		t.Errorf("SessionId is still the stale %q", saved)
+	}
+	if len(changes) != 1 || changes[0] != current {
+		t.Errorf("session id changed to %v, want once to %q", changes, current)
+	}
+
+	client.SetSessionId(saved)
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if n := len(s.Requests()); n != 9 {
+		t.Errorf("server got %d requests after SetSessionId, want 9", n)
+	}
+	if len(changes) != 2 || changes[1] != current {
+		t.Errorf("session id changed to %v, want twice to %q", changes, current)
+	}
+}
+
+// newCookieProxy puts a proxy in front of s that sets a cookie on the first
+// response, the 409 of the handshake, and refuses requests without it
+// afterwards.
+func newCookieProxy(s *transmissiontest.Server) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("proxy-session"); err != nil {
+			if r.Header.Get("X-Transmission-Session-Id") != "" {
+				http.Error(w, "missing proxy cookie", http.StatusForbidden)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "proxy-session", Value: "abc", Path: "/"})
+		}
+		s.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+func TestCookies(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	proxy := newCookieProxy(s)
+	defer proxy.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range []transmission.Option{transmission.WithCookies(), transmission.WithCookieJar(jar)} {
+		client := newClient(t, proxy.URL, opt)
+		for i := 0; i < 2; i++ {
+			if _, err := client.GetSession(); err != nil {
+				t.Fatalf("GetSession: %v", err)
+			}
+		}
+	}
+	requests := s.Requests()
+	if len(requests) != 6 {
+		t.Fatalf("server got %d requests, want 6", len(requests))
+	}
+	for i, req := range requests {
+		// The first request of each client has no cookie yet.
+		if i%3 == 0 {
+			continue
+		}
+		if cookie := req.Header.Get("Cookie"); cookie != "proxy-session=abc" {
+			t.Errorf("request %d sent cookie %q, want proxy-session=abc", i, cookie)
+		}
+	}
+
+	client := newClient(t, proxy.URL)
+	var authErr *transmission.AuthError
+	if _, err := client.GetSession(); !errors.As(err, &authErr) {
+		t.Errorf("GetSession without cookies = %v, want AuthError", err)
+	}
+}
+
+func TestCookiesWithHTTPClient(t *testing.T) {
+	for _, opt := range []transmission.Option{transmission.WithCookies(), transmission.WithCookieJar(nil)} {
+		if _, err := transmission.NewWithOptions("localhost", opt, transmission.WithHTTPClient(&http.Client{})); err == nil {
+			t.Error("NewWithOptions combined a cookie option with WithHTTPClient")
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	for _, tc := range []struct {
+		opts []transmission.Option
+		want string
+	}{
+		{nil, transmission.DefaultUserAgent},
+		{[]transmission.Option{transmission.WithUserAgent("my-poller/1.2")}, "my-poller/1.2"},
+	} {
+		before := len(s.Requests())
+		client := newClient(t, s.URL, tc.opts...)
+		if _, err := client.GetSession(); err != nil {
+			t.Fatalf("GetSession: %v", err)
+		}
+		requests := s.Requests()[before:]
+		if len(requests) != 2 {
+			t.Fatalf("server got %d requests, want the 409 and its retry", len(requests))
+		}
+		for i, req := range requests {
+			if got := req.Header.Get("User-Agent"); got != tc.want {
+				t.Errorf("request %d sent User-Agent %q, want %q", i, got, tc.want)
+			}
+		}
+	}
+	if !strings.HasPrefix(transmission.DefaultUserAgent, "transmission_go_api") {
+		t.Errorf("DefaultUserAgent = %q, want it to name the library", transmission.DefaultUserAgent)
+	}
+	if _, err := transmission.NewWithOptions("localhost", transmission.WithUserAgent("")); err == nil {
+		t.Error("WithUserAgent accepted an empty user agent")
+	}
+}
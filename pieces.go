@@ -0,0 +1,72 @@
+package transmission_go_api
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// PieceMap decodes the pieces bitfield into one bool per piece, true for the
+// pieces we have. Both the pieces and pieceCount fields must have been
+// fetched.
+func (t *Torrent) PieceMap() ([]bool, error) {
+	if t.Pieces == "" {
+		return nil, fmt.Errorf("torrent %d: pieces not fetched", t.Id)
+	}
+	bits, err := base64.StdEncoding.DecodeString(t.Pieces)
+	if err != nil {
+		return nil, fmt.Errorf("torrent %d: invalid pieces: %v", t.Id, err)
+	}
+	if t.PieceCount <= 0 {
+		return nil, fmt.Errorf("torrent %d: pieceCount not fetched", t.Id)
+	}
+	if int64(len(bits))*8 < t.PieceCount {
+		return nil, fmt.Errorf("torrent %d: pieces has %d bits for %d pieces", t.Id, len(bits)*8, t.PieceCount)
+	}
+	pieces := make([]bool, t.PieceCount)
+	for i := range pieces {
+		// The first piece is the most significant bit of the first byte.
+		pieces[i] = bits[i/8]&(0x80>>uint(i%8)) != 0
+	}
+	return pieces, nil
+}
+
+// PieceCompletion returns the fraction of pieces we have, or 0 if the pieces
+// can't be decoded.
+func (t *Torrent) PieceCompletion() float64 {
+	pieces, err := t.PieceMap()
+	if err != nil {
+		return 0
+	}
+	have := 0
+	for _, p := range pieces {
+		if p {
+			have++
+		}
+	}
+	return float64(have) / float64(len(pieces))
+}
+
+// PieceRun is a run of consecutive pieces that we either all have or all
+// miss.
+type PieceRun struct {
+	Have  bool
+	Start int64
+	Count int64
+}
+
+// PieceRuns returns the pieces as runs, e.g. for drawing a progress bar.
+func (t *Torrent) PieceRuns() ([]PieceRun, error) {
+	pieces, err := t.PieceMap()
+	if err != nil {
+		return nil, err
+	}
+	var runs []PieceRun
+	for i, p := range pieces {
+		if len(runs) > 0 && runs[len(runs)-1].Have == p {
+			runs[len(runs)-1].Count++
+			continue
+		}
+		runs = append(runs, PieceRun{Have: p, Start: int64(i), Count: 1})
+	}
+	return runs, nil
+}
@@ -0,0 +1,69 @@
+package transmission_go_api_test
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+)
+
+func TestPieceMap(t *testing.T) {
+	// 10 pieces: the second byte only uses its two top bits, the others are
+	// padding and must be ignored.
+	torrent := &transmission.Torrent{
+		Id:         1,
+		Pieces:     base64.StdEncoding.EncodeToString([]byte{0xC1, 0x7F}),
+		PieceCount: 10,
+	}
+	pieces, err := torrent.PieceMap()
+	if err != nil {
+		t.Fatalf("PieceMap: %v", err)
+	}
+	want := []bool{true, true, false, false, false, false, false, true, false, true}
+	if !reflect.DeepEqual(pieces, want) {
+		t.Errorf("PieceMap = %v, want %v", pieces, want)
+	}
+	if got := torrent.PieceCompletion(); got != 0.4 {
+		t.Errorf("PieceCompletion = %v, want 0.4", got)
+	}
+	runs, err := torrent.PieceRuns()
+	if err != nil {
+		t.Fatalf("PieceRuns: %v", err)
+	}
+	wantRuns := []transmission.PieceRun{
+		{Have: true, Start: 0, Count: 2},
+		{Have: false, Start: 2, Count: 5},
+		{Have: true, Start: 7, Count: 1},
+		{Have: false, Start: 8, Count: 1},
+		{Have: true, Start: 9, Count: 1},
+	}
+	if !reflect.DeepEqual(runs, wantRuns) {
+		t.Errorf("PieceRuns = %+v, want %+v", runs, wantRuns)
+	}
+}
+
+func TestPieceMapErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		pieces     string
+		pieceCount int64
+	}{
+		{"pieces not fetched", "", 10},
+		{"corrupted base64", "w!A=", 10},
+		{"truncated base64", "wEA", 10},
+		{"pieceCount not fetched", "wEA=", 0},
+		{"too few bits", "wEA=", 17},
+	} {
+		torrent := &transmission.Torrent{Id: 1, Pieces: tc.pieces, PieceCount: tc.pieceCount}
+		if pieces, err := torrent.PieceMap(); err == nil {
+			t.Errorf("%s: PieceMap = %v, want an error", tc.name, pieces)
+		}
+		if runs, err := torrent.PieceRuns(); err == nil {
+			t.Errorf("%s: PieceRuns = %v, want an error", tc.name, runs)
+		}
+		if got := torrent.PieceCompletion(); got != 0 {
+			t.Errorf("%s: PieceCompletion = %v, want 0", tc.name, got)
+		}
+	}
+}
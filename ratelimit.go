@@ -0,0 +1,92 @@
+package transmission_go_api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithRateLimit limits the client to rps RPCs per second on average, with
+// bursts of up to burst calls, across all goroutines using it. A call over
+// the limit waits for its turn or until its context is done. Each attempt
+// made by WithRetry waits for its own turn. The retry after a 409 session
+// handshake is part of the same attempt and is not limited.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *config) error {
+		if rps <= 0 || math.IsInf(rps, 0) || math.IsNaN(rps) {
+			return fmt.Errorf("invalid rate limit %v", rps)
+		}
+		if burst < 1 {
+			return fmt.Errorf("invalid rate limit burst %d", burst)
+		}
+		c.rateLimit = &rateLimiter{rate: rps, burst: float64(burst), tokens: float64(burst)}
+		return nil
+	}
+}
+
+// rateLimiter is a token bucket holding up to burst tokens, refilled at rate
+// tokens per second. Tokens may go negative: each waiting call reserves one,
+// so waiters are served in the order they arrived.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a token reserved by a call that gave up waiting.
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// waitRateLimit blocks until the rate limit lets the call through.
+func (t *Transmission) waitRateLimit(ctx context.Context) error {
+	l := t.config.rateLimit
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	atomic.AddInt64(&t.stats.rateLimitWaits, 1)
+	atomic.AddInt64(&t.stats.rateLimitWaiting, 1)
+	defer atomic.AddInt64(&t.stats.rateLimitWaiting, -1)
+	start := time.Now()
+	err := sleepContext(ctx, delay)
+	atomic.AddInt64(&t.stats.rateLimitWaitNanos, int64(time.Since(start)))
+	if err != nil {
+		l.cancel()
+	}
+	return err
+}
+
+// limitedExchange is exchange after waiting for the rate limit.
+func (t *Transmission) limitedExchange(ctx context.Context, req interface{}, resp interface{}) error {
+	if err := t.waitRateLimit(ctx); err != nil {
+		return err
+	}
+	return t.exchange(ctx, req, resp)
+}
@@ -0,0 +1,113 @@
+package transmission_go_api_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+func TestRateLimit(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.GetSession(); err != nil {
+			t.Fatalf("GetSession: %v", err)
+		}
+	}
+	// The burst covers the first call and its 409 retry, the others wait
+	// 50ms each.
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("4 calls took %v, want at least 150ms", elapsed)
+	}
+	stats := client.Stats()
+	if stats.RateLimitWaits != 3 {
+		t.Errorf("RateLimitWaits = %d, want 3", stats.RateLimitWaits)
+	}
+	if stats.RateLimitWaitTime < 140*time.Millisecond {
+		t.Errorf("RateLimitWaitTime = %v, want at least 150ms", stats.RateLimitWaitTime)
+	}
+	if stats.RateLimitWaiting != 0 {
+		t.Errorf("RateLimitWaiting = %d, want 0", stats.RateLimitWaiting)
+	}
+}
+
+func TestRateLimitSharedByGoroutines(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRateLimit(100, 1))
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetSession(); err != nil {
+				t.Errorf("GetSession: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("10 parallel calls took %v, want at least 100ms", elapsed)
+	}
+}
+
+func TestRateLimitEachRetry(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRateLimit(20, 1), transmission.WithRetry(3, time.Millisecond))
+	s.FailNext(http.StatusServiceUnavailable, "")
+	s.FailNext(http.StatusServiceUnavailable, "")
+
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got := client.Stats().RateLimitWaits; got != 2 {
+		t.Errorf("RateLimitWaits = %d, want 2, one per retry", got)
+	}
+}
+
+func TestRateLimitContext(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRateLimit(0.1, 1))
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetSessionContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("GetSessionContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetSessionContext returned after %v", elapsed)
+	}
+	if got := len(s.Requests()); got != 2 {
+		t.Errorf("server got %d requests, want 2", got)
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		rps   float64
+		burst int
+	}{{0, 1}, {-1, 1}, {1, 0}} {
+		if _, err := transmission.NewWithOptions("localhost", transmission.WithRateLimit(tc.rps, tc.burst)); err == nil {
+			t.Errorf("WithRateLimit(%v, %d) succeeded", tc.rps, tc.burst)
+		}
+	}
+}
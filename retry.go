@@ -0,0 +1,96 @@
+package transmission_go_api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// retryPolicy configures the retries of failed RPCs, see WithRetry.
+type retryPolicy struct {
+	maxAttempts   int
+	baseDelay     time.Duration
+	nonIdempotent bool
+}
+
+// nonIdempotentMethods are not retried unless WithRetryNonIdempotent is
+// given, as running them twice does not have the same effect as running them
+// once.
+var nonIdempotentMethods = map[string]bool{
+	"torrent-add":         true,
+	"torrent-rename-path": true,
+	"queue-move-up":       true,
+	"queue-move-down":     true,
+	"session-close":       true,
+}
+
+// exchangeWithRetry runs exchange, retrying transient failures as configured
+// by WithRetry.
+func (t *Transmission) exchangeWithRetry(ctx context.Context, req interface{}, resp interface{}) error {
+	policy := t.config.retry
+	if policy == nil {
+		return t.limitedExchange(ctx, req, resp)
+	}
+	if r, ok := req.(rpcRequest); ok && nonIdempotentMethods[r.rpcMethod()] && !policy.nonIdempotent {
+		return t.limitedExchange(ctx, req, resp)
+	}
+	var err error
+	for attempt := 0; attempt < policy.maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := backoff(policy.baseDelay, attempt)
+			t.config.logger.Warnf("retrying transmission RPC in %v after: %v", delay, err)
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
+		}
+		if trace, ok := ctx.Value(rpcTraceKey{}).(*rpcTrace); ok {
+			trace.retries = attempt
+		}
+		err = t.limitedExchange(ctx, req, resp)
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// retryable reports whether err may go away by trying again, like while the
+// daemon restarts behind a reverse proxy.
+func retryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns the delay before the given retry: baseDelay doubled for
+// each earlier retry, with the upper half randomized.
+func backoff(baseDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay << uint(attempt-1)
+	if delay <= 0 {
+		return baseDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
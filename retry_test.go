@@ -0,0 +1,109 @@
+package transmission_go_api_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+func TestRetryFlakyDaemon(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRetry(3, time.Millisecond))
+	s.FailNext(http.StatusServiceUnavailable, "starting")
+	s.FailNext(http.StatusBadGateway, "proxy error")
+
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	// Two failures, the 409 handshake and the answer.
+	if got := len(s.Requests()); got != 4 {
+		t.Errorf("server got %d requests, want 4", got)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRetry(3, time.Millisecond))
+	for i := 0; i < 4; i++ {
+		s.FailNext(http.StatusServiceUnavailable, "down")
+	}
+
+	var httpErr *transmission.HTTPError
+	if _, err := client.GetSession(); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GetSession = %v, want a 503 HTTPError", err)
+	}
+	if got := len(s.Requests()); got != 3 {
+		t.Errorf("server got %d requests, want 3", got)
+	}
+}
+
+func TestNoRetryOnAuthErrors(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		s := transmissiontest.NewServer()
+		client := newClient(t, s.URL, transmission.WithRetry(3, time.Millisecond))
+		s.FailNext(status, "<h1>denied</h1>")
+
+		var authErr *transmission.AuthError
+		if _, err := client.GetSession(); !errors.As(err, &authErr) || authErr.StatusCode != status {
+			t.Errorf("GetSession = %v, want a %d AuthError", err, status)
+		}
+		if got := len(s.Requests()); got != 1 {
+			t.Errorf("%d: server got %d requests, want 1", status, got)
+		}
+		s.Close()
+	}
+}
+
+func TestNoRetryOnRPCError(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRetry(3, time.Millisecond))
+	s.SetResult("session-get", "session unavailable")
+
+	var rpcErr *transmission.RPCError
+	if _, err := client.GetSession(); !errors.As(err, &rpcErr) {
+		t.Fatalf("GetSession = %v, want RPCError", err)
+	}
+	// The 409 handshake and the failed call.
+	if got := len(s.Requests()); got != 2 {
+		t.Errorf("server got %d requests, want 2", got)
+	}
+}
+
+func TestNoRetryOnTorrentAdd(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRetry(3, time.Millisecond))
+	s.FailNext(http.StatusServiceUnavailable, "")
+
+	var httpErr *transmission.HTTPError
+	if _, err := client.Add("magnet:?xt=urn:btih:abc"); !errors.As(err, &httpErr) {
+		t.Fatalf("Add = %v, want HTTPError", err)
+	}
+	if got := countMethod(s, "torrent-add"); got != 1 {
+		t.Errorf("server got %d torrent-add requests, want 1", got)
+	}
+	if n := len(s.Torrents()); n != 0 {
+		t.Errorf("server has %d torrents, want 0", n)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL, transmission.WithRetry(3, time.Millisecond), transmission.WithRetryNonIdempotent())
+	s.FailNext(http.StatusServiceUnavailable, "")
+
+	if _, err := client.Add("magnet:?xt=urn:btih:abc"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if n := len(s.Torrents()); n != 1 {
+		t.Errorf("server has %d torrents, want 1", n)
+	}
+}
@@ -0,0 +1,986 @@
+package transmission_go_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"syscall"
+	"time"
+)
+
+// 4.1 Session Arguments
+
+// Units describes the units the daemon uses when formatting sizes and
+// speeds.
+type Units struct {
+	SpeedUnits  []string `json:"speed-units,omitempty"`
+	SpeedBytes  int64    `json:"speed-bytes,omitempty"`
+	SizeUnits   []string `json:"size-units,omitempty"`
+	SizeBytes   int64    `json:"size-bytes,omitempty"`
+	MemoryUnits []string `json:"memory-units,omitempty"`
+	MemoryBytes int64    `json:"memory-bytes,omitempty"`
+}
+
+// Session holds the daemon's configuration as returned by session-get.
+// Speeds are in KB/s.
+type Session struct {
+	AltSpeedDown                     int64      `json:"alt-speed-down,omitempty"`
+	AltSpeedEnabled                  bool       `json:"alt-speed-enabled,omitempty"`
+	AltSpeedTimeBegin                TimeOfDay  `json:"alt-speed-time-begin,omitempty"`
+	AltSpeedTimeDay                  DayMask    `json:"alt-speed-time-day,omitempty"`
+	AltSpeedTimeEnabled              bool       `json:"alt-speed-time-enabled,omitempty"`
+	AltSpeedTimeEnd                  TimeOfDay  `json:"alt-speed-time-end,omitempty"`
+	AltSpeedUp                       int64      `json:"alt-speed-up,omitempty"`
+	AntiBruteForceEnabled            bool       `json:"anti-brute-force-enabled,omitempty"`
+	AntiBruteForceThreshold          int64      `json:"anti-brute-force-threshold,omitempty"`
+	BlocklistEnabled                 bool       `json:"blocklist-enabled,omitempty"`
+	BlocklistSize                    int64      `json:"blocklist-size,omitempty"`
+	BlocklistURL                     string     `json:"blocklist-url,omitempty"`
+	CacheSizeMB                      int64      `json:"cache-size-mb,omitempty"`
+	ConfigDir                        string     `json:"config-dir,omitempty"`
+	DefaultTrackers                  string     `json:"default-trackers,omitempty"`
+	DHTEnabled                       bool       `json:"dht-enabled,omitempty"`
+	DownloadDir                      string     `json:"download-dir,omitempty"`
+	DownloadDirFreeSpace             int64      `json:"download-dir-free-space,omitempty"`
+	DownloadQueueEnabled             bool       `json:"download-queue-enabled,omitempty"`
+	DownloadQueueSize                int64      `json:"download-queue-size,omitempty"`
+	Encryption                       Encryption `json:"encryption,omitempty"`
+	IdleSeedingLimit                 int64      `json:"idle-seeding-limit,omitempty"`
+	IdleSeedingLimitEnabled          bool       `json:"idle-seeding-limit-enabled,omitempty"`
+	IncompleteDir                    string     `json:"incomplete-dir,omitempty"`
+	IncompleteDirEnabled             bool       `json:"incomplete-dir-enabled,omitempty"`
+	LPDEnabled                       bool       `json:"lpd-enabled,omitempty"`
+	PeerLimitGlobal                  int64      `json:"peer-limit-global,omitempty"`
+	PeerLimitPerTorrent              int64      `json:"peer-limit-per-torrent,omitempty"`
+	PeerPort                         int64      `json:"peer-port,omitempty"`
+	PeerPortRandomOnStart            bool       `json:"peer-port-random-on-start,omitempty"`
+	PEXEnabled                       bool       `json:"pex-enabled,omitempty"`
+	PortForwardingEnabled            bool       `json:"port-forwarding-enabled,omitempty"`
+	QueueStalledEnabled              bool       `json:"queue-stalled-enabled,omitempty"`
+	QueueStalledMinutes              int64      `json:"queue-stalled-minutes,omitempty"`
+	RenamePartialFiles               bool       `json:"rename-partial-files,omitempty"`
+	RPCVersion                       int64      `json:"rpc-version,omitempty"`
+	RPCVersionMinimum                int64      `json:"rpc-version-minimum,omitempty"`
+	RPCVersionSemver                 string     `json:"rpc-version-semver,omitempty"`
+	ScriptTorrentAddedEnabled        bool       `json:"script-torrent-added-enabled,omitempty"`
+	ScriptTorrentAddedFilename       string     `json:"script-torrent-added-filename,omitempty"`
+	ScriptTorrentDoneEnabled         bool       `json:"script-torrent-done-enabled,omitempty"`
+	ScriptTorrentDoneFilename        string     `json:"script-torrent-done-filename,omitempty"`
+	ScriptTorrentDoneSeedingEnabled  bool       `json:"script-torrent-done-seeding-enabled,omitempty"`
+	ScriptTorrentDoneSeedingFilename string     `json:"script-torrent-done-seeding-filename,omitempty"`
+	SeedQueueEnabled                 bool       `json:"seed-queue-enabled,omitempty"`
+	SeedQueueSize                    int64      `json:"seed-queue-size,omitempty"`
+	SeedRatioLimit                   float64    `json:"seedRatioLimit,omitempty"`
+	SeedRatioLimited                 bool       `json:"seedRatioLimited,omitempty"`
+	SessionId                        string     `json:"session-id,omitempty"`
+	SpeedLimitDown                   int64      `json:"speed-limit-down,omitempty"`
+	SpeedLimitDownEnabled            bool       `json:"speed-limit-down-enabled,omitempty"`
+	SpeedLimitUp                     int64      `json:"speed-limit-up,omitempty"`
+	SpeedLimitUpEnabled              bool       `json:"speed-limit-up-enabled,omitempty"`
+	StartAddedTorrents               bool       `json:"start-added-torrents,omitempty"`
+	TCPEnabled                       bool       `json:"tcp-enabled,omitempty"`
+	TrashOriginalTorrentFiles        bool       `json:"trash-original-torrent-files,omitempty"`
+	Units                            *Units     `json:"units,omitempty"`
+	UTPEnabled                       bool       `json:"utp-enabled,omitempty"`
+	Version                          string     `json:"version,omitempty"`
+}
+
+// TimeOfDay is a time of day in minutes after midnight, as used by the
+// alternative speed scheduler.
+type TimeOfDay int64
+
+// TimeOfDayFromDuration converts d, the time elapsed since midnight, to a
+// TimeOfDay. Seconds are truncated.
+func TimeOfDayFromDuration(d time.Duration) TimeOfDay {
+	return TimeOfDay(d / time.Minute)
+}
+
+// Duration returns the time elapsed since midnight.
+func (t TimeOfDay) Duration() time.Duration {
+	return time.Duration(t) * time.Minute
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", t/60, t%60)
+}
+
+func (t TimeOfDay) valid() bool {
+	return t >= 0 && t < 24*60
+}
+
+// DayMask is a set of week days, as used by the alternative speed scheduler.
+type DayMask int64
+
+const (
+	DaySunday    DayMask = 1 << time.Sunday
+	DayMonday    DayMask = 1 << time.Monday
+	DayTuesday   DayMask = 1 << time.Tuesday
+	DayWednesday DayMask = 1 << time.Wednesday
+	DayThursday  DayMask = 1 << time.Thursday
+	DayFriday    DayMask = 1 << time.Friday
+	DaySaturday  DayMask = 1 << time.Saturday
+
+	DayWeekdays = DayMonday | DayTuesday | DayWednesday | DayThursday | DayFriday
+	DayWeekend  = DaySunday | DaySaturday
+	DayAll      = DayWeekdays | DayWeekend
+)
+
+// Days returns the DayMask holding days.
+func Days(days ...time.Weekday) DayMask {
+	var m DayMask
+	for _, d := range days {
+		m |= 1 << d
+	}
+	return m
+}
+
+// Has reports whether day is in m.
+func (m DayMask) Has(day time.Weekday) bool {
+	return m&(1<<day) != 0
+}
+
+// Encryption is the daemon's peer connection encryption preference.
+type Encryption string
+
+const (
+	EncryptionRequired  Encryption = "required"
+	EncryptionPreferred Encryption = "preferred"
+	EncryptionTolerated Encryption = "tolerated"
+)
+
+func (e Encryption) valid() bool {
+	switch e {
+	case EncryptionRequired, EncryptionPreferred, EncryptionTolerated:
+		return true
+	}
+	return false
+}
+
+type sessionGetResponse struct {
+	*responseBase
+	Arguments *Session `json:"arguments"`
+}
+
+type sessionGetRequestPayload struct {
+	Fields []string `json:"fields,omitempty"`
+}
+
+type sessionGetRequest struct {
+	*requestBase
+	Arguments *sessionGetRequestPayload `json:"arguments,omitempty"`
+}
+
+// GetSession returns the daemon's configuration.
+func (t *Transmission) GetSession() (*Session, error) {
+	return t.GetSessionContext(context.Background())
+}
+
+// GetSessionContext is like GetSession, but with a context.
+func (t *Transmission) GetSessionContext(ctx context.Context) (*Session, error) {
+	return t.GetSessionFieldsContext(ctx, nil)
+}
+
+// GetSessionFields returns only the given fields of the daemon's
+// configuration, e.g. "download-dir"; the other fields are left at their zero
+// value. Daemons older than RPC version 16 ignore fields and return
+// everything.
+func (t *Transmission) GetSessionFields(fields []string) (*Session, error) {
+	return t.GetSessionFieldsContext(context.Background(), fields)
+}
+
+// GetSessionFieldsContext is like GetSessionFields, but with a context.
+func (t *Transmission) GetSessionFieldsContext(ctx context.Context, fields []string) (*Session, error) {
+	req := sessionGetRequest{
+		requestBase: &requestBase{
+			Method: "session-get",
+		},
+	}
+	if len(fields) > 0 {
+		req.Arguments = &sessionGetRequestPayload{Fields: fields}
+	}
+	resp := &sessionGetResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Arguments == nil {
+		return nil, fmt.Errorf("session-get response without arguments")
+	}
+	return resp.Arguments, nil
+}
+
+// RPCVersion returns the daemon's RPC version. It is asked from the daemon
+// the first time, and again after the daemon restarted.
+func (t *Transmission) RPCVersion() (int64, error) {
+	return t.RPCVersionContext(context.Background())
+}
+
+// RPCVersionContext is like RPCVersion, but with a context.
+func (t *Transmission) RPCVersionContext(ctx context.Context) (int64, error) {
+	return t.rpcVersion(ctx)
+}
+
+// RPCVersionMinimum returns the oldest RPC version the daemon still
+// supports, cached like RPCVersion.
+func (t *Transmission) RPCVersionMinimum() (int64, error) {
+	return t.RPCVersionMinimumContext(context.Background())
+}
+
+// RPCVersionMinimumContext is like RPCVersionMinimum, but with a context.
+func (t *Transmission) RPCVersionMinimumContext(ctx context.Context) (int64, error) {
+	if _, err := t.rpcVersion(ctx); err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cachedRPCVersionMinimum, nil
+}
+
+// versionCall is a session-get for the RPC version in flight.
+type versionCall struct {
+	done chan struct{}
+	err  error
+}
+
+// rpcVersion returns the daemon's RPC version, asking the daemon only when
+// it is not cached. Concurrent callers share one session-get.
+func (t *Transmission) rpcVersion(ctx context.Context) (int64, error) {
+	for {
+		t.mu.Lock()
+		version := t.cachedRPCVersion
+		call := t.versionCall
+		if version == 0 && call == nil {
+			call = &versionCall{done: make(chan struct{})}
+			t.versionCall = call
+			t.mu.Unlock()
+			return t.fetchRPCVersion(ctx, call)
+		}
+		t.mu.Unlock()
+		if version != 0 {
+			return version, nil
+		}
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		// The context of the caller who asked is not ours, try again.
+		if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+			continue
+		}
+		if call.err != nil {
+			return 0, call.err
+		}
+	}
+}
+
+// fetchRPCVersion asks the daemon for the RPC version for call.
+func (t *Transmission) fetchRPCVersion(ctx context.Context, call *versionCall) (int64, error) {
+	session, err := t.GetSessionFieldsContext(ctx, []string{"rpc-version", "rpc-version-minimum"})
+	t.mu.Lock()
+	if err == nil {
+		t.cachedRPCVersion = session.RPCVersion
+		t.cachedRPCVersionMinimum = session.RPCVersionMinimum
+	}
+	call.err = err
+	t.versionCall = nil
+	t.mu.Unlock()
+	close(call.done)
+	if err != nil {
+		return 0, err
+	}
+	return session.RPCVersion, nil
+}
+
+// ErrUnsupportedByDaemon is matched by the UnsupportedError returned when a
+// feature needs a newer daemon.
+var ErrUnsupportedByDaemon = errors.New("unsupported by this daemon")
+
+// UnsupportedError is returned when a feature needs a newer RPC version than
+// the daemon's.
+type UnsupportedError struct {
+	Feature         string
+	RPCVersion      int64 // The daemon's.
+	RequiredVersion int64
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is %v (RPC version %d, need %d)", e.Feature, ErrUnsupportedByDaemon, e.RPCVersion, e.RequiredVersion)
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupportedByDaemon
+}
+
+// requireRPCVersion returns an UnsupportedError if the daemon's RPC version
+// is older than min.
+func (t *Transmission) requireRPCVersion(ctx context.Context, min int64, feature string) error {
+	version, err := t.rpcVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version < min {
+		return &UnsupportedError{Feature: feature, RPCVersion: version, RequiredVersion: min}
+	}
+	return nil
+}
+
+// SessionSetArgs holds the session properties that can be changed. Only
+// non-nil fields are sent, so unrelated settings are left alone.
+type SessionSetArgs struct {
+	SpeedLimitDown        *int64 `json:"speed-limit-down,omitempty"` // KB/s
+	SpeedLimitDownEnabled *bool  `json:"speed-limit-down-enabled,omitempty"`
+	SpeedLimitUp          *int64 `json:"speed-limit-up,omitempty"` // KB/s
+	SpeedLimitUpEnabled   *bool  `json:"speed-limit-up-enabled,omitempty"`
+
+	// Alternative speed limits ("turtle mode") and their schedule.
+	AltSpeedEnabled     *bool      `json:"alt-speed-enabled,omitempty"`
+	AltSpeedDown        *int64     `json:"alt-speed-down,omitempty"` // KB/s
+	AltSpeedUp          *int64     `json:"alt-speed-up,omitempty"`   // KB/s
+	AltSpeedTimeEnabled *bool      `json:"alt-speed-time-enabled,omitempty"`
+	AltSpeedTimeBegin   *TimeOfDay `json:"alt-speed-time-begin,omitempty"`
+	AltSpeedTimeEnd     *TimeOfDay `json:"alt-speed-time-end,omitempty"`
+	AltSpeedTimeDay     *DayMask   `json:"alt-speed-time-day,omitempty"`
+
+	// Directories and file handling.
+	DownloadDir               *string `json:"download-dir,omitempty"`
+	IncompleteDir             *string `json:"incomplete-dir,omitempty"`
+	IncompleteDirEnabled      *bool   `json:"incomplete-dir-enabled,omitempty"`
+	RenamePartialFiles        *bool   `json:"rename-partial-files,omitempty"`
+	StartAddedTorrents        *bool   `json:"start-added-torrents,omitempty"`
+	TrashOriginalTorrentFiles *bool   `json:"trash-original-torrent-files,omitempty"`
+
+	// Protocol options.
+	Encryption *Encryption `json:"encryption,omitempty"`
+	PEXEnabled *bool       `json:"pex-enabled,omitempty"`
+	DHTEnabled *bool       `json:"dht-enabled,omitempty"`
+	LPDEnabled *bool       `json:"lpd-enabled,omitempty"`
+	UTPEnabled *bool       `json:"utp-enabled,omitempty"`
+
+	// Blocklist. The read-only blocklist-size is deliberately missing: it is
+	// only reported by session-get and changed by blocklist-update.
+	BlocklistURL     *string `json:"blocklist-url,omitempty"`
+	BlocklistEnabled *bool   `json:"blocklist-enabled,omitempty"`
+
+	// Queueing.
+	DownloadQueueSize    *int64 `json:"download-queue-size,omitempty"`
+	DownloadQueueEnabled *bool  `json:"download-queue-enabled,omitempty"`
+	SeedQueueSize        *int64 `json:"seed-queue-size,omitempty"`
+	SeedQueueEnabled     *bool  `json:"seed-queue-enabled,omitempty"`
+	QueueStalledEnabled  *bool  `json:"queue-stalled-enabled,omitempty"`
+	QueueStalledMinutes  *int64 `json:"queue-stalled-minutes,omitempty"`
+
+	// Default seeding limits. They only apply to torrents whose
+	// SeedRatioMode is SeedRatioModeGlobal, respectively whose SeedIdleMode
+	// is SeedIdleModeGlobal; SeedRatioModeSingle and SeedIdleModeSingle
+	// torrents use their own limits and the Unlimited modes ignore limits
+	// altogether. See SetSeedRatio and SetSeedIdleLimit.
+	SeedRatioLimit          *float64 `json:"seedRatioLimit,omitempty"`
+	SeedRatioLimited        *bool    `json:"seedRatioLimited,omitempty"`
+	IdleSeedingLimit        *int64   `json:"idle-seeding-limit,omitempty"` // minutes
+	IdleSeedingLimitEnabled *bool    `json:"idle-seeding-limit-enabled,omitempty"`
+
+	// Scripts run by the daemon when a torrent finishes downloading or
+	// seeding. Filenames must be absolute paths on the daemon's machine. The
+	// seeding script needs Transmission 4.0.
+	ScriptTorrentDoneEnabled         *bool   `json:"script-torrent-done-enabled,omitempty"`
+	ScriptTorrentDoneFilename        *string `json:"script-torrent-done-filename,omitempty"`
+	ScriptTorrentDoneSeedingEnabled  *bool   `json:"script-torrent-done-seeding-enabled,omitempty"`
+	ScriptTorrentDoneSeedingFilename *string `json:"script-torrent-done-seeding-filename,omitempty"`
+
+	// Peer listening port. Pair a port change with a port test to verify it
+	// is reachable, see SetPeerPortAndVerify.
+	PeerPort              *int64 `json:"peer-port,omitempty"`
+	PeerPortRandomOnStart *bool  `json:"peer-port-random-on-start,omitempty"`
+	PortForwardingEnabled *bool  `json:"port-forwarding-enabled,omitempty"`
+
+	// Trackers added to all public torrents, in the trackerList format. See
+	// SetDefaultTrackers.
+	DefaultTrackers *string `json:"default-trackers,omitempty"`
+
+	// Anti-brute-force protection of the RPC interface. Beware: once
+	// enabled, the daemon stops answering clients after threshold failed
+	// authentication attempts, including this one if its credentials are
+	// wrong, and only a daemon restart lifts the ban. Banned clients get
+	// ErrForbidden.
+	AntiBruteForceEnabled   *bool  `json:"anti-brute-force-enabled,omitempty"`
+	AntiBruteForceThreshold *int64 `json:"anti-brute-force-threshold,omitempty"`
+}
+
+func (a *SessionSetArgs) validate() error {
+	if a.AltSpeedTimeBegin != nil && !a.AltSpeedTimeBegin.valid() {
+		return fmt.Errorf("invalid alt-speed-time-begin %d", *a.AltSpeedTimeBegin)
+	}
+	if a.AltSpeedTimeEnd != nil && !a.AltSpeedTimeEnd.valid() {
+		return fmt.Errorf("invalid alt-speed-time-end %d", *a.AltSpeedTimeEnd)
+	}
+	if a.AltSpeedTimeDay != nil && (*a.AltSpeedTimeDay < 0 || *a.AltSpeedTimeDay > DayAll) {
+		return fmt.Errorf("invalid alt-speed-time-day %d", *a.AltSpeedTimeDay)
+	}
+	if a.Encryption != nil && !a.Encryption.valid() {
+		return fmt.Errorf("invalid encryption %q", *a.Encryption)
+	}
+	if a.BlocklistURL != nil && *a.BlocklistURL != "" {
+		if err := validateBlocklistURL(*a.BlocklistURL); err != nil {
+			return err
+		}
+	}
+	if a.SeedRatioLimit != nil && *a.SeedRatioLimit < 0 {
+		return fmt.Errorf("invalid seedRatioLimit %v", *a.SeedRatioLimit)
+	}
+	if a.IdleSeedingLimit != nil && *a.IdleSeedingLimit < 0 {
+		return fmt.Errorf("invalid idle-seeding-limit %d", *a.IdleSeedingLimit)
+	}
+	if a.DownloadQueueSize != nil && *a.DownloadQueueSize < 0 {
+		return fmt.Errorf("invalid download-queue-size %d", *a.DownloadQueueSize)
+	}
+	if a.SeedQueueSize != nil && *a.SeedQueueSize < 0 {
+		return fmt.Errorf("invalid seed-queue-size %d", *a.SeedQueueSize)
+	}
+	if a.QueueStalledMinutes != nil && *a.QueueStalledMinutes < 0 {
+		return fmt.Errorf("invalid queue-stalled-minutes %d", *a.QueueStalledMinutes)
+	}
+	// The daemon runs scripts from an unpredictable working directory.
+	if f := a.ScriptTorrentDoneFilename; f != nil && *f != "" && !isAbsDaemonPath(*f) {
+		return fmt.Errorf("script-torrent-done-filename must be an absolute path, got %q", *f)
+	}
+	if f := a.ScriptTorrentDoneSeedingFilename; f != nil && *f != "" && !isAbsDaemonPath(*f) {
+		return fmt.Errorf("script-torrent-done-seeding-filename must be an absolute path, got %q", *f)
+	}
+	if a.PeerPort != nil && (*a.PeerPort < 1 || *a.PeerPort > 65535) {
+		return fmt.Errorf("invalid peer-port %d", *a.PeerPort)
+	}
+	if a.DefaultTrackers != nil {
+		for _, tier := range ParseTrackerList(*a.DefaultTrackers) {
+			for _, announce := range tier {
+				if err := validateAnnounceURL(announce); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if a.AntiBruteForceThreshold != nil && *a.AntiBruteForceThreshold < 1 {
+		return fmt.Errorf("invalid anti-brute-force-threshold %d", *a.AntiBruteForceThreshold)
+	}
+	if a.DownloadDir != nil && *a.DownloadDir == "" {
+		return fmt.Errorf("empty download-dir")
+	}
+	return nil
+}
+
+// isAbsDaemonPath reports whether p is absolute on the daemon's machine,
+// which may run a different OS than the client.
+func isAbsDaemonPath(p string) bool {
+	if path.IsAbs(p) {
+		return true
+	}
+	// Windows drive path such as C:\ or C:/.
+	return len(p) >= 3 && p[1] == ':' && (p[2] == '\\' || p[2] == '/') &&
+		((p[0] >= 'a' && p[0] <= 'z') || (p[0] >= 'A' && p[0] <= 'Z'))
+}
+
+type sessionSetRequest struct {
+	*requestBase
+	Arguments *SessionSetArgs `json:"arguments"`
+}
+
+// validateBlocklistURL checks that the daemon can download the blocklist
+// from blocklistURL.
+func validateBlocklistURL(blocklistURL string) error {
+	u, err := url.Parse(blocklistURL)
+	if err != nil {
+		return fmt.Errorf("invalid blocklist-url %q: %v", blocklistURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid blocklist-url %q: scheme %q is not http or https", blocklistURL, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid blocklist-url %q: missing host", blocklistURL)
+	}
+	return nil
+}
+
+// SetSession applies all the non-nil fields of args to the daemon's
+// configuration.
+func (t *Transmission) SetSession(args *SessionSetArgs) error {
+	return t.SetSessionContext(context.Background(), args)
+}
+
+// SetSessionContext is like SetSession, but with a context.
+func (t *Transmission) SetSessionContext(ctx context.Context, args *SessionSetArgs) error {
+	if args == nil {
+		return fmt.Errorf("no session properties to set")
+	}
+	if err := args.validate(); err != nil {
+		return err
+	}
+	req := sessionSetRequest{
+		requestBase: &requestBase{
+			Method: "session-set",
+		},
+		Arguments: args,
+	}
+	resp := &torrentRequestsResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetSessionSpeedLimits sets the global speed limits in KB/s. A limit is only
+// enforced when its enabled flag is true.
+func (t *Transmission) SetSessionSpeedLimits(downKbps int64, downEnabled bool, upKbps int64, upEnabled bool) error {
+	return t.SetSessionSpeedLimitsContext(context.Background(), downKbps, downEnabled, upKbps, upEnabled)
+}
+
+// SetSessionSpeedLimitsContext is like SetSessionSpeedLimits, but with a context.
+func (t *Transmission) SetSessionSpeedLimitsContext(ctx context.Context, downKbps int64, downEnabled bool, upKbps int64, upEnabled bool) error {
+	return t.SetSessionContext(ctx, &SessionSetArgs{
+		SpeedLimitDown:        &downKbps,
+		SpeedLimitDownEnabled: &downEnabled,
+		SpeedLimitUp:          &upKbps,
+		SpeedLimitUpEnabled:   &upEnabled,
+	})
+}
+
+// SetPeerPortAndVerify sets the peer listening port and then asks the daemon
+// to check whether the port is reachable from the internet.
+func (t *Transmission) SetPeerPortAndVerify(port int) (open bool, err error) {
+	return t.SetPeerPortAndVerifyContext(context.Background(), port)
+}
+
+// SetPeerPortAndVerifyContext is like SetPeerPortAndVerify, but with a context.
+func (t *Transmission) SetPeerPortAndVerifyContext(ctx context.Context, port int) (open bool, err error) {
+	peerPort := int64(port)
+	err = t.SetSessionContext(ctx, &SessionSetArgs{PeerPort: &peerPort})
+	if err != nil {
+		return false, err
+	}
+	return t.PortTestContext(ctx)
+}
+
+// 4.2 Session Statistics
+
+// TransferStats are the transfer totals of one or all daemon sessions.
+type TransferStats struct {
+	UploadedBytes   int64 `json:"uploadedBytes,omitempty"`
+	DownloadedBytes int64 `json:"downloadedBytes,omitempty"`
+	FilesAdded      int64 `json:"filesAdded,omitempty"`
+	SessionCount    int64 `json:"sessionCount,omitempty"`
+	SecondsActive   int64 `json:"secondsActive,omitempty"`
+}
+
+// SessionStats are the daemon-wide statistics. Speeds are in B/s.
+type SessionStats struct {
+	ActiveTorrentCount int64          `json:"activeTorrentCount,omitempty"`
+	DownloadSpeed      int64          `json:"downloadSpeed,omitempty"`
+	PausedTorrentCount int64          `json:"pausedTorrentCount,omitempty"`
+	TorrentCount       int64          `json:"torrentCount,omitempty"`
+	UploadSpeed        int64          `json:"uploadSpeed,omitempty"`
+	CumulativeStats    *TransferStats `json:"cumulative-stats,omitempty"`
+	CurrentStats       *TransferStats `json:"current-stats,omitempty"`
+}
+
+type sessionStatsResponse struct {
+	*responseBase
+	Arguments *SessionStats `json:"arguments"`
+}
+
+// GetSessionStats returns the daemon-wide transfer statistics.
+func (t *Transmission) GetSessionStats() (*SessionStats, error) {
+	return t.GetSessionStatsContext(context.Background())
+}
+
+// GetSessionStatsContext is like GetSessionStats, but with a context.
+func (t *Transmission) GetSessionStatsContext(ctx context.Context) (*SessionStats, error) {
+	req := &requestBase{
+		Method: "session-stats",
+	}
+	resp := &sessionStatsResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Arguments == nil {
+		return nil, fmt.Errorf("session-stats response without arguments")
+	}
+	return resp.Arguments, nil
+}
+
+// 4.3 Blocklist
+type blocklistUpdateResponsePayload struct {
+	BlocklistSize int64 `json:"blocklist-size"`
+}
+
+type blocklistUpdateResponse struct {
+	*responseBase
+	Arguments *blocklistUpdateResponsePayload `json:"arguments"`
+}
+
+// UpdateBlocklist makes the daemon download its blocklist from the
+// configured blocklist-url and returns the number of rules in the new list.
+// The daemon only answers once the download is done, which can take tens of
+// seconds, longer than a client-wide WithTimeout may allow. Use a context
+// from WithCallTimeout, or with its own deadline, with UpdateBlocklistContext
+// to give the call more time.
+func (t *Transmission) UpdateBlocklist() (size int64, err error) {
+	return t.UpdateBlocklistContext(context.Background())
+}
+
+// UpdateBlocklistContext is like UpdateBlocklist, but with a context.
+func (t *Transmission) UpdateBlocklistContext(ctx context.Context) (size int64, err error) {
+	req := &requestBase{
+		Method: "blocklist-update",
+	}
+	resp := &blocklistUpdateResponse{responseBase: &responseBase{}}
+	err = t.doRPC(ctx, req, resp)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Arguments == nil {
+		return 0, fmt.Errorf("blocklist-update response without arguments")
+	}
+	return resp.Arguments.BlocklistSize, nil
+}
+
+// 4.4 Port Checking
+type portTestResponsePayload struct {
+	PortIsOpen bool `json:"port-is-open"`
+}
+
+type portTestResponse struct {
+	*responseBase
+	Arguments *portTestResponsePayload `json:"arguments"`
+}
+
+// PortTest asks the daemon whether its peer port is reachable from the
+// internet. A closed port is reported as false with a nil error; an error
+// means the check itself failed.
+func (t *Transmission) PortTest() (open bool, err error) {
+	return t.PortTestContext(context.Background())
+}
+
+// PortTestContext is like PortTest, but with a context.
+func (t *Transmission) PortTestContext(ctx context.Context) (open bool, err error) {
+	req := &requestBase{
+		Method: "port-test",
+	}
+	resp := &portTestResponse{responseBase: &responseBase{}}
+	err = t.doRPC(ctx, req, resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.Arguments == nil {
+		return false, fmt.Errorf("port-test response without arguments")
+	}
+	return resp.Arguments.PortIsOpen, nil
+}
+
+// SetDefaultTrackers sets the trackers the daemon adds to public torrents,
+// given as tiers of announce URLs. An empty tiers removes them. It needs RPC
+// version 17 (Transmission 4.0).
+func (t *Transmission) SetDefaultTrackers(tiers [][]string) error {
+	return t.SetDefaultTrackersContext(context.Background(), tiers)
+}
+
+// SetDefaultTrackersContext is like SetDefaultTrackers, but with a context.
+func (t *Transmission) SetDefaultTrackersContext(ctx context.Context, tiers [][]string) error {
+	for i, tier := range tiers {
+		if len(tier) == 0 {
+			return fmt.Errorf("tracker tier %d is empty", i)
+		}
+	}
+	if err := t.requireRPCVersion(ctx, 17, "default-trackers"); err != nil {
+		return err
+	}
+	list := BuildTrackerList(tiers)
+	return t.SetSessionContext(ctx, &SessionSetArgs{DefaultTrackers: &list})
+}
+
+// TorrentUpdateError is the error of one torrent of a call updating many.
+type TorrentUpdateError struct {
+	Id  int64
+	Err error
+}
+
+func (e *TorrentUpdateError) Error() string {
+	return fmt.Sprintf("torrent %d: %v", e.Id, e.Err)
+}
+
+func (e *TorrentUpdateError) Unwrap() error {
+	return e.Err
+}
+
+// TorrentUpdateErrors holds the errors of the torrents a call updating many
+// failed for. The other torrents were updated.
+type TorrentUpdateErrors []*TorrentUpdateError
+
+func (e TorrentUpdateErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d torrents failed, first %v", len(e), e[0])
+}
+
+func (e TorrentUpdateErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ApplyDefaultTrackersToExisting appends the session's default trackers to
+// every existing public torrent. Private torrents are skipped, as are
+// trackers a torrent already has, so running it again is a no-op. A torrent
+// that fails does not stop the others; the failures are returned together
+// in a TorrentUpdateErrors.
+func (t *Transmission) ApplyDefaultTrackersToExisting(ctx context.Context) error {
+	session, err := t.GetSessionContext(ctx)
+	if err != nil {
+		return err
+	}
+	defaults := ParseTrackerList(session.DefaultTrackers)
+	if len(defaults) == 0 {
+		return nil
+	}
+	torrents, err := t.get(ctx, nil, []string{FieldId, FieldIsPrivate, FieldTrackerList})
+	if err != nil {
+		return err
+	}
+	var errs TorrentUpdateErrors
+	for _, torrent := range torrents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if torrent.IsPrivate {
+			continue
+		}
+		tiers := ParseTrackerList(torrent.TrackerList)
+		known := map[string]bool{}
+		for _, tier := range tiers {
+			for _, announce := range tier {
+				known[announce] = true
+			}
+		}
+		changed := false
+		for _, tier := range defaults {
+			var missing []string
+			for _, announce := range tier {
+				if !known[announce] {
+					missing = append(missing, announce)
+				}
+			}
+			if len(missing) > 0 {
+				tiers = append(tiers, missing)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := t.SetTrackerListContext(ctx, []int64{torrent.Id}, tiers); err != nil {
+			errs = append(errs, &TorrentUpdateError{Id: torrent.Id, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// 4.5 Session Shutdown
+
+// CloseSession shuts the daemon down.
+func (t *Transmission) CloseSession() error {
+	return t.CloseSessionContext(context.Background())
+}
+
+// CloseSessionContext is like CloseSession, but with a context.
+func (t *Transmission) CloseSessionContext(ctx context.Context) error {
+	req := &requestBase{
+		Method: "session-close",
+	}
+	resp := &torrentRequestsResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		// The daemon may go away before answering; that is what we asked for.
+		if isConnClosed(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// isConnClosed reports whether err means the daemon closed the connection
+// after receiving the request.
+func isConnClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// 4.6 Free Space
+type freeSpaceRequestPayload struct {
+	Path string `json:"path"`
+}
+
+type freeSpaceRequest struct {
+	*requestBase
+	Arguments *freeSpaceRequestPayload `json:"arguments"`
+}
+
+// FreeSpaceResult describes the disk holding a directory on the daemon's
+// machine. TotalSize is only reported by Transmission 4.0 and later.
+type FreeSpaceResult struct {
+	Path      string `json:"path,omitempty"`
+	SizeBytes int64  `json:"size-bytes,omitempty"`
+	TotalSize int64  `json:"total_size,omitempty"`
+}
+
+type freeSpaceResponse struct {
+	*responseBase
+	Arguments *FreeSpaceResult `json:"arguments"`
+}
+
+// FreeSpace returns the number of free bytes in the directory at path on the
+// daemon's machine.
+func (t *Transmission) FreeSpace(path string) (bytes int64, err error) {
+	return t.FreeSpaceContext(context.Background(), path)
+}
+
+// FreeSpaceContext is like FreeSpace, but with a context.
+func (t *Transmission) FreeSpaceContext(ctx context.Context, path string) (bytes int64, err error) {
+	res, err := t.GetFreeSpaceContext(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return res.SizeBytes, nil
+}
+
+// GetFreeSpace is like FreeSpace, but also returns the disk's total size
+// when the daemon reports it.
+func (t *Transmission) GetFreeSpace(path string) (*FreeSpaceResult, error) {
+	return t.GetFreeSpaceContext(context.Background(), path)
+}
+
+// GetFreeSpaceContext is like GetFreeSpace, but with a context.
+func (t *Transmission) GetFreeSpaceContext(ctx context.Context, path string) (*FreeSpaceResult, error) {
+	req := freeSpaceRequest{
+		requestBase: &requestBase{
+			Method: "free-space",
+		},
+		Arguments: &freeSpaceRequestPayload{
+			Path: path,
+		},
+	}
+	resp := &freeSpaceResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		return nil, fmt.Errorf("checking free space of %q: %w", path, err)
+	}
+	if resp.Arguments == nil {
+		return nil, fmt.Errorf("free-space response without arguments")
+	}
+	return resp.Arguments, nil
+}
+
+// 4.7 Bandwidth Groups
+
+// BandwidthGroup is a named set of speed limits shared by all the torrents
+// assigned to it with SetGroup. Speeds are in KB/s.
+type BandwidthGroup struct {
+	Name                  string `json:"name"`
+	HonorsSessionLimits   bool   `json:"honorsSessionLimits"`
+	SpeedLimitDown        int64  `json:"speed-limit-down"`
+	SpeedLimitDownEnabled bool   `json:"speed-limit-down-enabled"`
+	SpeedLimitUp          int64  `json:"speed-limit-up"`
+	SpeedLimitUpEnabled   bool   `json:"speed-limit-up-enabled"`
+}
+
+type groupGetRequestPayload struct {
+	Group []string `json:"group,omitempty"`
+}
+
+type groupGetRequest struct {
+	*requestBase
+	Arguments *groupGetRequestPayload `json:"arguments"`
+}
+
+type groupGetResponsePayload struct {
+	Group []*BandwidthGroup `json:"group"`
+}
+
+type groupGetResponse struct {
+	*responseBase
+	Arguments *groupGetResponsePayload `json:"arguments"`
+}
+
+type groupSetRequest struct {
+	*requestBase
+	Arguments *BandwidthGroup `json:"arguments"`
+}
+
+// GetGroups returns the bandwidth groups with the given names, or all groups
+// if names is empty. It needs RPC version 17 (Transmission 4.0).
+func (t *Transmission) GetGroups(names []string) ([]*BandwidthGroup, error) {
+	return t.GetGroupsContext(context.Background(), names)
+}
+
+// GetGroupsContext is like GetGroups, but with a context.
+func (t *Transmission) GetGroupsContext(ctx context.Context, names []string) ([]*BandwidthGroup, error) {
+	if err := t.requireRPCVersion(ctx, 17, "bandwidth groups"); err != nil {
+		return nil, err
+	}
+	req := groupGetRequest{
+		requestBase: &requestBase{
+			Method: "group-get",
+		},
+		Arguments: &groupGetRequestPayload{
+			Group: names,
+		},
+	}
+	resp := &groupGetResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Arguments == nil {
+		return nil, fmt.Errorf("group-get response without arguments")
+	}
+	return resp.Arguments.Group, nil
+}
+
+// SetBandwidthGroup creates the bandwidth group or replaces its settings. It
+// needs RPC version 17 (Transmission 4.0).
+//
+// Not to be confused with SetGroup, which assigns torrents to a group.
+func (t *Transmission) SetBandwidthGroup(group *BandwidthGroup) error {
+	return t.SetBandwidthGroupContext(context.Background(), group)
+}
+
+// SetBandwidthGroupContext is like SetBandwidthGroup, but with a context.
+func (t *Transmission) SetBandwidthGroupContext(ctx context.Context, group *BandwidthGroup) error {
+	if group == nil || group.Name == "" {
+		return fmt.Errorf("bandwidth group without a name")
+	}
+	if err := t.requireRPCVersion(ctx, 17, "bandwidth groups"); err != nil {
+		return err
+	}
+	req := groupSetRequest{
+		requestBase: &requestBase{
+			Method: "group-set",
+		},
+		Arguments: group,
+	}
+	resp := &torrentRequestsResponse{responseBase: &responseBase{}}
+	err := t.doRPC(ctx, req, resp)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package transmission_go_api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+func TestSetSessionBlocklist(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetSessionField("blocklist-size", 1234)
+	client := newClient(t, s.URL)
+
+	blocklistURL := "https://example.com/level1.gz"
+	enabled := true
+	err := client.SetSession(&transmission.SessionSetArgs{
+		BlocklistURL:     &blocklistURL,
+		BlocklistEnabled: &enabled,
+	})
+	if err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	requests := s.Requests()
+	var args map[string]interface{}
+	if err := json.Unmarshal(requests[len(requests)-1].Arguments, &args); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"blocklist-url": blocklistURL, "blocklist-enabled": true}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("session-set arguments %v, want %v", args, want)
+	}
+
+	session, err := client.GetSession()
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session.BlocklistURL != blocklistURL || !session.BlocklistEnabled || session.BlocklistSize != 1234 {
+		t.Errorf("session has blocklist %q, enabled %v, size %d; want %q, true, 1234",
+			session.BlocklistURL, session.BlocklistEnabled, session.BlocklistSize, blocklistURL)
+	}
+}
+
+func TestSessionSetArgsReadOnlyFields(t *testing.T) {
+	// Set every field, so that each one ends up in the JSON.
+	var args transmission.SessionSetArgs
+	v := reflect.ValueOf(&args).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.Ptr {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+	}
+	data, err := json.Marshal(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["blocklist-url"]; !ok {
+		t.Fatalf("session-set arguments %s lack blocklist-url", data)
+	}
+	for _, name := range []string{"blocklist-size", "rpc-version", "rpc-version-minimum", "version", "config-dir"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("session-set arguments include the read-only %s", name)
+		}
+	}
+}
+
+func TestSetSessionBlocklistURLInvalid(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	for _, blocklistURL := range []string{
+		"example.com/level1.gz",
+		"/var/lib/transmission/blocklist",
+		"file:///var/lib/transmission/blocklist",
+		"ftp://example.com/level1.gz",
+		"http://",
+		"https:///level1.gz",
+		"http://exa mple.com/",
+	} {
+		err := client.SetSession(&transmission.SessionSetArgs{BlocklistURL: &blocklistURL})
+		if err == nil {
+			t.Errorf("SetSession accepted blocklist-url %q", blocklistURL)
+		}
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Errorf("server got %d requests, want 0", n)
+	}
+
+	// An empty URL clears the setting.
+	empty := ""
+	if err := client.SetSession(&transmission.SessionSetArgs{BlocklistURL: &empty}); err != nil {
+		t.Errorf("SetSession with an empty blocklist-url: %v", err)
+	}
+}
+
+func TestUpdateBlocklistSlow(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetSessionField("blocklist-size", 1234)
+	client := newClient(t, s.URL, transmission.WithTimeout(50*time.Millisecond))
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	s.SetLatency(200 * time.Millisecond)
+
+	// The client-wide timeout applies to blocklist-update too.
+	if _, err := client.UpdateBlocklist(); !errors.Is(err, transmission.ErrTimeout) {
+		t.Errorf("UpdateBlocklist = %v, want ErrTimeout", err)
+	}
+	for name, ctx := range map[string]context.Context{
+		"WithCallTimeout": transmission.WithCallTimeout(context.Background(), time.Second),
+		"no timeout":      transmission.WithCallTimeout(context.Background(), 0),
+	} {
+		size, err := client.UpdateBlocklistContext(ctx)
+		if err != nil {
+			t.Errorf("%s: UpdateBlocklistContext: %v", name, err)
+		} else if size != 1234 {
+			t.Errorf("%s: UpdateBlocklistContext = %d, want 1234", name, size)
+		}
+	}
+}
+
+// newHangupServer puts a proxy in front of s that hangs up on session-close
+// requests past the handshake instead of answering, as a daemon shutting down
+// may. With reset, the connection is reset rather than closed.
+func newHangupServer(t *testing.T, s *transmissiontest.Server, reset bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		var req struct {
+			Method string `json:"method"`
+		}
+		json.Unmarshal(body, &req)
+		if req.Method != "session-close" || r.Header.Get("X-Transmission-Session-Id") == "" {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			s.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijacking connection: %v", err)
+			return
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok && reset {
+			tcp.SetLinger(0)
+		}
+		conn.Close()
+	}))
+}
+
+func TestCloseSessionHangup(t *testing.T) {
+	for _, reset := range []bool{false, true} {
+		s := transmissiontest.NewServer()
+		hangup := newHangupServer(t, s, reset)
+		client := newClient(t, hangup.URL)
+		if err := client.CloseSession(); err != nil {
+			t.Errorf("reset %v: CloseSession = %v, want nil", reset, err)
+		}
+		hangup.Close()
+		s.Close()
+	}
+}
+
+func TestCloseSessionErrors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+
+	s.FailNext(http.StatusInternalServerError, "internal error")
+	var httpErr *transmission.HTTPError
+	if err := client.CloseSession(); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("CloseSession = %v, want a 500 HTTPError", err)
+	}
+	s.SetResult("session-close", "shutting down is not allowed")
+	var rpcErr *transmission.RPCError
+	if err := client.CloseSession(); !errors.As(err, &rpcErr) {
+		t.Errorf("CloseSession = %v, want RPCError", err)
+	}
+
+	// A daemon that is already gone is not a successful close.
+	s.Close()
+	if err := client.CloseSession(); err == nil {
+		t.Error("CloseSession succeeded with the daemon gone")
+	}
+}
+
+func TestSetSessionOnlySetFields(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	up := int64(100)
+	no := false
+
+	checkArguments(t, s, "session-set", []argumentsTest{
+		{
+			"SetSessionSpeedLimits",
+			func() error { return client.SetSessionSpeedLimits(0, false, 250, true) },
+			`{"speed-limit-down":0,"speed-limit-down-enabled":false,"speed-limit-up":250,"speed-limit-up-enabled":true}`,
+		},
+		{
+			"one field",
+			func() error { return client.SetSession(&transmission.SessionSetArgs{SpeedLimitUp: &up}) },
+			`{"speed-limit-up":100}`,
+		},
+		{
+			"false",
+			func() error { return client.SetSession(&transmission.SessionSetArgs{SpeedLimitUpEnabled: &no}) },
+			`{"speed-limit-up-enabled":false}`,
+		},
+		{
+			"no fields",
+			func() error { return client.SetSession(&transmission.SessionSetArgs{}) },
+			`{}`,
+		},
+	})
+
+	session, err := client.GetSession()
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session.SpeedLimitUp != 100 || session.SpeedLimitUpEnabled || session.SpeedLimitDownEnabled {
+		t.Errorf("session has speed-limit-up %d, enabled %v, speed-limit-down-enabled %v; want 100, false, false",
+			session.SpeedLimitUp, session.SpeedLimitUpEnabled, session.SpeedLimitDownEnabled)
+	}
+	// Settings that were not sent are left alone.
+	if session.DownloadDir != "/downloads" {
+		t.Errorf("session download-dir = %q, want /downloads", session.DownloadDir)
+	}
+	if err := client.SetSession(nil); err == nil {
+		t.Error("SetSession accepted nil arguments")
+	}
+}
+
+// queueSettings returns the queue settings of s.
+func queueSettings(s *transmission.Session) []interface{} {
+	return []interface{}{
+		s.DownloadQueueSize, s.DownloadQueueEnabled,
+		s.SeedQueueSize, s.SeedQueueEnabled,
+		s.QueueStalledEnabled, s.QueueStalledMinutes,
+	}
+}
+
+func TestSessionQueueRoundTrip(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	for _, want := range []transmission.Session{
+		{
+			DownloadQueueSize:    2,
+			DownloadQueueEnabled: true,
+			SeedQueueSize:        10,
+			SeedQueueEnabled:     true,
+			QueueStalledEnabled:  true,
+			QueueStalledMinutes:  30,
+		},
+		// Zero and false must be sent too, to shrink or turn off the queues.
+		{},
+	} {
+		want := want
+		err := client.SetSession(&transmission.SessionSetArgs{
+			DownloadQueueSize:    &want.DownloadQueueSize,
+			DownloadQueueEnabled: &want.DownloadQueueEnabled,
+			SeedQueueSize:        &want.SeedQueueSize,
+			SeedQueueEnabled:     &want.SeedQueueEnabled,
+			QueueStalledEnabled:  &want.QueueStalledEnabled,
+			QueueStalledMinutes:  &want.QueueStalledMinutes,
+		})
+		if err != nil {
+			t.Fatalf("SetSession: %v", err)
+		}
+		var sent map[string]interface{}
+		if err := json.Unmarshal([]byte(sentArguments(t, s, "session-set")), &sent); err != nil {
+			t.Fatal(err)
+		}
+		if len(sent) != 6 {
+			t.Errorf("session-set sent %v, want the six queue settings", sent)
+		}
+
+		got, err := client.GetSession()
+		if err != nil {
+			t.Fatalf("GetSession: %v", err)
+		}
+		if !reflect.DeepEqual(queueSettings(got), queueSettings(&want)) {
+			t.Errorf("queue settings read back as %v, want %v", queueSettings(got), queueSettings(&want))
+		}
+	}
+
+	negative := int64(-1)
+	for _, args := range []*transmission.SessionSetArgs{
+		{DownloadQueueSize: &negative},
+		{SeedQueueSize: &negative},
+		{QueueStalledMinutes: &negative},
+	} {
+		if err := client.SetSession(args); err == nil {
+			t.Errorf("SetSession accepted a negative queue setting")
+		}
+	}
+}
+
+func TestSessionAltSpeedRoundTrip(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	enabled := true
+	down, up := int64(50), int64(0)
+	begin := transmission.TimeOfDayFromDuration(22*time.Hour + 30*time.Minute)
+	end := transmission.TimeOfDay(0) // Midnight.
+	days := transmission.Days(time.Saturday, time.Sunday)
+	checkArguments(t, s, "session-set", []argumentsTest{
+		{
+			"alt speed",
+			func() error {
+				return client.SetSession(&transmission.SessionSetArgs{
+					AltSpeedEnabled:     &enabled,
+					AltSpeedDown:        &down,
+					AltSpeedUp:          &up,
+					AltSpeedTimeEnabled: &enabled,
+					AltSpeedTimeBegin:   &begin,
+					AltSpeedTimeEnd:     &end,
+					AltSpeedTimeDay:     &days,
+				})
+			},
+			`{"alt-speed-enabled":true,"alt-speed-down":50,"alt-speed-up":0,"alt-speed-time-enabled":true,
+			  "alt-speed-time-begin":1350,"alt-speed-time-end":0,"alt-speed-time-day":65}`,
+		},
+		{
+			"speed limits",
+			func() error { return client.SetSessionSpeedLimits(1000, true, 0, false) },
+			`{"speed-limit-down":1000,"speed-limit-down-enabled":true,"speed-limit-up":0,"speed-limit-up-enabled":false}`,
+		},
+	})
+
+	session, err := client.GetSession()
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if !session.AltSpeedEnabled || session.AltSpeedDown != 50 || session.AltSpeedUp != 0 || !session.AltSpeedTimeEnabled {
+		t.Errorf("alt speed read back as enabled %v, down %d, up %d, scheduled %v; want true, 50, 0, true",
+			session.AltSpeedEnabled, session.AltSpeedDown, session.AltSpeedUp, session.AltSpeedTimeEnabled)
+	}
+	if session.AltSpeedTimeBegin != begin || session.AltSpeedTimeBegin.String() != "22:30" ||
+		session.AltSpeedTimeBegin.Duration() != 22*time.Hour+30*time.Minute {
+		t.Errorf("alt-speed-time-begin read back as %v, want 22:30", session.AltSpeedTimeBegin)
+	}
+	if session.AltSpeedTimeEnd != end {
+		t.Errorf("alt-speed-time-end read back as %v, want 00:00", session.AltSpeedTimeEnd)
+	}
+	if session.AltSpeedTimeDay != transmission.DayWeekend || !session.AltSpeedTimeDay.Has(time.Sunday) || session.AltSpeedTimeDay.Has(time.Monday) {
+		t.Errorf("alt-speed-time-day read back as %d, want the weekend", session.AltSpeedTimeDay)
+	}
+	if session.SpeedLimitDown != 1000 || !session.SpeedLimitDownEnabled || session.SpeedLimitUp != 0 || session.SpeedLimitUpEnabled {
+		t.Errorf("speed limits read back as down %d, enabled %v, up %d, enabled %v; want 1000, true, 0, false",
+			session.SpeedLimitDown, session.SpeedLimitDownEnabled, session.SpeedLimitUp, session.SpeedLimitUpEnabled)
+	}
+
+	invalidTime := transmission.TimeOfDay(24 * 60)
+	invalidDays := transmission.DayAll + 1
+	for name, args := range map[string]*transmission.SessionSetArgs{
+		"begin": {AltSpeedTimeBegin: &invalidTime},
+		"end":   {AltSpeedTimeEnd: &invalidTime},
+		"days":  {AltSpeedTimeDay: &invalidDays},
+	} {
+		if err := client.SetSession(args); err == nil {
+			t.Errorf("SetSession accepted an invalid alt speed %s", name)
+		}
+	}
+}
+
+func TestApplyDefaultTrackersToExisting(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetSessionField("default-trackers", "https://one.example.com/announce\n\nudp://two.example.org:6969\n")
+	public := s.AddTorrent(transmission.Torrent{Name: "public", TrackerList: "https://own.example.net/announce\n"})
+	private := s.AddTorrent(transmission.Torrent{Name: "private", IsPrivate: true, TrackerList: "https://private.example.net/announce\n"})
+	partial := s.AddTorrent(transmission.Torrent{Name: "partial", TrackerList: "udp://two.example.org:6969\n"})
+	client := newClient(t, s.URL)
+
+	if err := client.ApplyDefaultTrackersToExisting(context.Background()); err != nil {
+		t.Fatalf("ApplyDefaultTrackersToExisting: %v", err)
+	}
+	want := map[int64]string{
+		public:  "https://own.example.net/announce\n\nhttps://one.example.com/announce\n\nudp://two.example.org:6969",
+		private: "https://private.example.net/announce\n",
+		partial: "udp://two.example.org:6969\n\nhttps://one.example.com/announce",
+	}
+	checkTrackerLists := func() {
+		t.Helper()
+		for _, torrent := range s.Torrents() {
+			if torrent.TrackerList != want[torrent.Id] {
+				t.Errorf("torrent %s has trackerList %q, want %q", torrent.Name, torrent.TrackerList, want[torrent.Id])
+			}
+		}
+	}
+	checkTrackerLists()
+	if n := countMethod(s, "torrent-set"); n != 2 {
+		t.Errorf("got %d torrent-set requests, want 2", n)
+	}
+
+	// Running it again changes nothing.
+	if err := client.ApplyDefaultTrackersToExisting(context.Background()); err != nil {
+		t.Fatalf("second ApplyDefaultTrackersToExisting: %v", err)
+	}
+	checkTrackerLists()
+	if n := countMethod(s, "torrent-set"); n != 2 {
+		t.Errorf("got %d torrent-set requests after the second run, want still 2", n)
+	}
+}
+
+func TestApplyDefaultTrackersToExistingErrors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetSessionField("default-trackers", "https://one.example.com/announce\n")
+	// The broken tracker list makes the update of the first torrent fail.
+	broken := s.AddTorrent(transmission.Torrent{Name: "broken", TrackerList: "not a tracker\n"})
+	fine := s.AddTorrent(transmission.Torrent{Name: "fine"})
+	client := newClient(t, s.URL)
+
+	err := client.ApplyDefaultTrackersToExisting(context.Background())
+	var errs transmission.TorrentUpdateErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Id != broken {
+		t.Fatalf("ApplyDefaultTrackersToExisting = %v, want a TorrentUpdateErrors for torrent %d", err, broken)
+	}
+	for _, torrent := range s.Torrents() {
+		if torrent.Id == fine && torrent.TrackerList != "https://one.example.com/announce" {
+			t.Errorf("torrent after the failed one has trackerList %q, want the default trackers", torrent.TrackerList)
+		}
+	}
+}
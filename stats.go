@@ -0,0 +1,86 @@
+package transmission_go_api
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds counters of a client's traffic, see Transmission.Stats.
+type Stats struct {
+	Requests      int64 // HTTP requests sent, including retries.
+	GzipResponses int64 // Responses the daemon sent gzip compressed.
+	ReceivedBytes int64 // Response body bytes as received.
+	DecodedBytes  int64 // Response body bytes after decompression.
+
+	// The rate limit counters stay zero without WithRateLimit.
+	RateLimitWaits    int64         // Calls that waited for the rate limit.
+	RateLimitWaiting  int64         // Calls waiting for the rate limit now.
+	RateLimitWaitTime time.Duration // Time spent waiting, summed over calls.
+}
+
+// clientStats holds the counters of Stats, accessed atomically.
+type clientStats struct {
+	requests      int64
+	gzipResponses int64
+	receivedBytes int64
+	decodedBytes  int64
+
+	rateLimitWaits     int64
+	rateLimitWaiting   int64
+	rateLimitWaitNanos int64
+}
+
+// Stats returns the client's traffic counters. The ratio of DecodedBytes to
+// ReceivedBytes shows the saving from compression.
+func (t *Transmission) Stats() Stats {
+	return Stats{
+		Requests:      atomic.LoadInt64(&t.stats.requests),
+		GzipResponses: atomic.LoadInt64(&t.stats.gzipResponses),
+		ReceivedBytes: atomic.LoadInt64(&t.stats.receivedBytes),
+		DecodedBytes:  atomic.LoadInt64(&t.stats.decodedBytes),
+
+		RateLimitWaits:    atomic.LoadInt64(&t.stats.rateLimitWaits),
+		RateLimitWaiting:  atomic.LoadInt64(&t.stats.rateLimitWaiting),
+		RateLimitWaitTime: time.Duration(atomic.LoadInt64(&t.stats.rateLimitWaitNanos)),
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readBody reads the response body, decompressing it if the daemon
+// compressed it. It reads at most limit decompressed bytes if limit is above
+// 0, as for the body of an error response.
+func (t *Transmission) readBody(body io.Reader, contentEncoding string, limit int64) ([]byte, error) {
+	wire := &countingReader{r: body}
+	var r io.Reader = wire
+	if contentEncoding == "gzip" {
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			atomic.AddInt64(&t.stats.receivedBytes, wire.n)
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+		atomic.AddInt64(&t.stats.gzipResponses, 1)
+	}
+	if limit > 0 {
+		r = io.LimitReader(r, limit)
+	}
+	bts, err := ioutil.ReadAll(r)
+	atomic.AddInt64(&t.stats.receivedBytes, wire.n)
+	atomic.AddInt64(&t.stats.decodedBytes, int64(len(bts)))
+	return bts, err
+}
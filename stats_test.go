@@ -0,0 +1,112 @@
+package transmission_go_api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+)
+
+// gzipServer is a fake daemon compressing its bodies, like a reverse proxy
+// in front of one. It answers with status and body after the session id
+// handshake.
+type gzipServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	body   string
+}
+
+func newGzipServer(t *testing.T) *gzipServer {
+	s := &gzipServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Transmission-Session-Id") != "gzip" {
+			w.Header().Set("X-Transmission-Session-Id", "gzip")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		s.mu.Lock()
+		status, body := s.status, s.body
+		s.mu.Unlock()
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(body))
+		gz.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(status)
+		w.Write(buf.Bytes())
+	}))
+	return s
+}
+
+func (s *gzipServer) respond(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.body = status, body
+}
+
+func TestGzipResponse(t *testing.T) {
+	s := newGzipServer(t)
+	defer s.Close()
+	downloadDir := strings.Repeat("/very/long/path", 100)
+	s.respond(http.StatusOK, `{"result":"success","tag":1,"arguments":{"download-dir":"`+downloadDir+`"}}`)
+	client := newClient(t, s.URL)
+
+	session, err := client.GetSession()
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session.DownloadDir != downloadDir {
+		t.Errorf("DownloadDir = %q", session.DownloadDir)
+	}
+	stats := client.Stats()
+	if stats.GzipResponses != 1 {
+		t.Errorf("GzipResponses = %d, want 1", stats.GzipResponses)
+	}
+	if stats.ReceivedBytes >= stats.DecodedBytes {
+		t.Errorf("ReceivedBytes = %d, DecodedBytes = %d, want fewer received", stats.ReceivedBytes, stats.DecodedBytes)
+	}
+}
+
+func TestGzipErrorBodies(t *testing.T) {
+	s := newGzipServer(t)
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	s.respond(http.StatusUnauthorized, "<h1>401: Unauthorized</h1>Unauthorized User")
+	var authErr *transmission.AuthError
+	if _, err := client.GetSession(); !errors.As(err, &authErr) {
+		t.Fatalf("GetSession = %v, want AuthError", err)
+	}
+	if authErr.Message != "401: Unauthorized Unauthorized User" {
+		t.Errorf("AuthError.Message = %q", authErr.Message)
+	}
+
+	s.respond(http.StatusBadGateway, "<html>502 Bad Gateway</html>")
+	var httpErr *transmission.HTTPError
+	if _, err := client.GetSession(); !errors.As(err, &httpErr) {
+		t.Fatalf("GetSession = %v, want HTTPError", err)
+	}
+	if httpErr.Body != "<html>502 Bad Gateway</html>" {
+		t.Errorf("HTTPError.Body = %q", httpErr.Body)
+	}
+
+	stats := client.Stats()
+	if stats.GzipResponses != 2 {
+		t.Errorf("GzipResponses = %d, want 2", stats.GzipResponses)
+	}
+	want := int64(len("<h1>401: Unauthorized</h1>Unauthorized User") + len("<html>502 Bad Gateway</html>"))
+	if stats.DecodedBytes != want {
+		t.Errorf("DecodedBytes = %d, want %d", stats.DecodedBytes, want)
+	}
+}
@@ -0,0 +1,82 @@
+package transmission_go_api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// WithTLSConfig makes the client use a copy of tlsConfig for https addresses.
+// An address without a scheme then defaults to https. Later TLS options
+// modify the copy.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) error {
+		if tlsConfig == nil {
+			return fmt.Errorf("nil TLS config")
+		}
+		c.tlsConfig = tlsConfig.Clone()
+		return nil
+	}
+}
+
+// WithCACertFile makes the client trust the PEM encoded certificates in the
+// file, in addition to the system's, as when the daemon sits behind a
+// reverse proxy with a certificate from a private CA.
+func WithCACertFile(path string) Option {
+	return func(c *config) error {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tlsConfig := c.ensureTLSConfig()
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs, err = x509.SystemCertPool()
+			if err != nil {
+				tlsConfig.RootCAs = x509.NewCertPool()
+			}
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", path)
+		}
+		return nil
+	}
+}
+
+// WithInsecureTLS disables the verification of the daemon's certificate. It
+// is only meant for testing.
+func WithInsecureTLS() Option {
+	return func(c *config) error {
+		c.ensureTLSConfig().InsecureSkipVerify = true
+		return nil
+	}
+}
+
+// WithClientCert makes the client present the certificate in the PEM encoded
+// files to servers asking for one, as reverse proxies doing mutual TLS do.
+// Basic auth is still sent for the daemon behind the proxy.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(c *config) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		return WithClientCertificate(cert)(c)
+	}
+}
+
+// WithClientCertificate is like WithClientCert with a loaded certificate.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *config) error {
+		tlsConfig := c.ensureTLSConfig()
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+		return nil
+	}
+}
+
+func (c *config) ensureTLSConfig() *tls.Config {
+	if c.tlsConfig == nil {
+		c.tlsConfig = &tls.Config{}
+	}
+	return c.tlsConfig
+}
@@ -0,0 +1,228 @@
+package transmission_go_api_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+// newTLSServer serves the fake daemon s over TLS. configure, if not nil, can
+// set up the server's tls.Config before it starts.
+func newTLSServer(s *transmissiontest.Server, configure func(*tls.Config)) *httptest.Server {
+	ts := httptest.NewUnstartedServer(s.Config.Handler)
+	ts.TLS = &tls.Config{}
+	if configure != nil {
+		configure(ts.TLS)
+	}
+	ts.StartTLS()
+	return ts
+}
+
+// hostPort returns the address of ts without the scheme.
+func hostPort(ts *httptest.Server) string {
+	return strings.TrimPrefix(ts.URL, "https://")
+}
+
+func TestTLSOptions(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	ts := newTLSServer(s, nil)
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opt  transmission.Option
+	}{
+		{"WithTLSConfig", transmission.WithTLSConfig(&tls.Config{RootCAs: pool})},
+		{"WithCACertFile", transmission.WithCACertFile(caFile)},
+		{"WithInsecureTLS", transmission.WithInsecureTLS()},
+	} {
+		// Without a scheme the address defaults to https as a TLS option
+		// is present.
+		for _, address := range []string{ts.URL, hostPort(ts)} {
+			client := newClient(t, address, tc.opt)
+			if _, err := client.GetSession(); err != nil {
+				t.Errorf("%s: GetSession(%q): %v", tc.name, address, err)
+			}
+		}
+	}
+}
+
+func TestTLSUntrusted(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	ts := newTLSServer(s, nil)
+	defer ts.Close()
+
+	client := newClient(t, ts.URL, transmission.WithTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()}))
+	if _, err := client.GetSession(); err == nil {
+		t.Error("GetSession trusted a certificate from an unknown CA")
+	}
+	// Plain http is still used without a TLS option.
+	client = newClient(t, hostPort(ts))
+	if _, err := client.GetSession(); err == nil {
+		t.Error("GetSession spoke http to a TLS server")
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Errorf("server got %d requests, want 0", n)
+	}
+}
+
+func TestCACertFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{path, path + ".missing"} {
+		if _, err := transmission.NewWithOptions("localhost", transmission.WithCACertFile(path)); err == nil {
+			t.Errorf("WithCACertFile(%q) succeeded", path)
+		}
+	}
+}
+
+// newClientCert returns a CA and a client certificate it signed.
+func newClientCert(t *testing.T) (*x509.Certificate, tls.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeKeyPair writes cert as PEM files and returns their paths.
+func writeKeyPair(t *testing.T, cert tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client.key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestClientCert(t *testing.T) {
+	ca, cert := newClientCert(t)
+	certFile, keyFile := writeKeyPair(t, cert)
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	ts := newTLSServer(s, func(config *tls.Config) {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = x509.NewCertPool()
+		config.ClientCAs.AddCert(ca)
+	})
+	defer ts.Close()
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(ts.Certificate())
+	trustServer := transmission.WithTLSConfig(&tls.Config{RootCAs: serverCAs})
+
+	client := newClient(t, ts.URL, trustServer)
+	if _, err := client.GetSession(); err == nil {
+		t.Error("GetSession succeeded without a client certificate")
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Fatalf("server got %d requests without a client certificate, want 0", n)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opt  transmission.Option
+	}{
+		{"WithClientCert", transmission.WithClientCert(certFile, keyFile)},
+		{"WithClientCertificate", transmission.WithClientCertificate(cert)},
+	} {
+		// The proxy terminates TLS, the daemon behind it still wants basic
+		// auth.
+		client := newClient(t, hostPort(ts), trustServer, tc.opt, transmission.WithBasicAuth("user", "pass"))
+		if _, err := client.GetSession(); err != nil {
+			t.Errorf("%s: GetSession: %v", tc.name, err)
+			continue
+		}
+		requests := s.Requests()
+		req := &http.Request{Header: requests[len(requests)-1].Header}
+		if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			t.Errorf("%s: basic auth = %q, %q, %v; want user, pass", tc.name, user, pass, ok)
+		}
+	}
+}
+
+func TestClientCertInvalid(t *testing.T) {
+	_, cert := newClientCert(t)
+	certFile, keyFile := writeKeyPair(t, cert)
+	for _, files := range [][2]string{
+		{certFile, certFile},
+		{keyFile, keyFile},
+		{certFile, keyFile + ".missing"},
+	} {
+		if _, err := transmission.NewWithOptions("localhost", transmission.WithClientCert(files[0], files[1])); err == nil {
+			t.Errorf("WithClientCert(%q, %q) succeeded", files[0], files[1])
+		}
+	}
+}
@@ -17,6 +17,7 @@ var (
 	startNow = flag.Int64("startnow", -1, "Start Now")
 	stop     = flag.Int64("stop", -1, "Stop")
 	remove   = flag.Int64("remove", -1, "Remove")
+	add      = flag.String("add", "", "Add .torrent file")
 )
 
 func main() {
@@ -31,7 +32,7 @@ func main() {
 			log.Fatalf("ListAll error: %v", err)
 		}
 		for _, torrent := range torrents {
-			fmt.Printf("%d: (Status %d) (Done: %.2f) %s\n", torrent.Id, torrent.Status, torrent.PercentDone*100, torrent.Name)
+			fmt.Printf("%d: (Status %s) (Done: %.2f) %s\n", torrent.Id, torrent.Status, torrent.PercentDone*100, torrent.Name)
 		}
 	} else if *start != -1 {
 		err := t.Start([]int64{*start})
@@ -53,5 +54,15 @@ func main() {
 		if err != nil {
 			log.Fatalf("Remove error: %v", err)
 		}
+	} else if *add != "" {
+		res, err := t.AddTorrentFile(*add)
+		if err != nil {
+			log.Fatalf("AddTorrentFile error: %v", err)
+		}
+		if res.Duplicate {
+			fmt.Printf("%d: %s (duplicate)\n", res.Torrent.Id, res.Torrent.Name)
+		} else {
+			fmt.Printf("%d: %s\n", res.Torrent.Id, res.Torrent.Name)
+		}
 	}
 }
@@ -0,0 +1,73 @@
+package transmission_go_api
+
+import (
+	"fmt"
+	"time"
+)
+
+// TorrentError is the kind of error a torrent is in, see Torrent.Error. The
+// details are in Torrent.ErrorString.
+type TorrentError int64
+
+const (
+	TorrentErrorOK             TorrentError = 0
+	TorrentErrorTrackerWarning TorrentError = 1
+	TorrentErrorTrackerError   TorrentError = 2
+	TorrentErrorLocal          TorrentError = 3
+)
+
+func (e TorrentError) String() string {
+	switch e {
+	case TorrentErrorOK:
+		return "ok"
+	case TorrentErrorTrackerWarning:
+		return "tracker warning"
+	case TorrentErrorTrackerError:
+		return "tracker error"
+	case TorrentErrorLocal:
+		return "local error"
+	}
+	return fmt.Sprintf("TorrentError(%d)", int64(e))
+}
+
+// HasTrackerError reports whether the tracker sent a warning or an error,
+// such as "unregistered torrent". Both the error and errorString fields must
+// have been fetched; a tracker error without a message is not reported.
+func (t *Torrent) HasTrackerError() bool {
+	return (t.Error == TorrentErrorTrackerWarning || t.Error == TorrentErrorTrackerError) && t.ErrorString != ""
+}
+
+// HasLocalError reports whether the torrent failed locally, for example
+// because the disk is full or the data is missing. Both the error and
+// errorString fields must have been fetched.
+func (t *Torrent) HasLocalError() bool {
+	return t.Error == TorrentErrorLocal && t.ErrorString != ""
+}
+
+// ETADuration returns the estimated time until the torrent is done, or false
+// if the daemon has no estimate, for example because the torrent is stopped,
+// stalled or finished.
+func (t *Torrent) ETADuration() (time.Duration, bool) {
+	if t.IsFinished {
+		return 0, false
+	}
+	return etaDuration(t.Eta)
+}
+
+// EtaIdleDuration returns the estimated time until the torrent hits its idle
+// seeding limit, or false if the daemon has no estimate.
+func (t *Torrent) EtaIdleDuration() (time.Duration, bool) {
+	if t.IsFinished {
+		return 0, false
+	}
+	return etaDuration(t.EtaIdle)
+}
+
+// etaDuration converts an eta in seconds, where -1 means not available and
+// -2 unknown.
+func etaDuration(eta int64) (time.Duration, bool) {
+	if eta < 0 {
+		return 0, false
+	}
+	return time.Duration(eta) * time.Second, true
+}
@@ -0,0 +1,33 @@
+package transmission_go_api_test
+
+import (
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+)
+
+func TestETADuration(t *testing.T) {
+	for _, tc := range []struct {
+		eta      int64
+		finished bool
+		want     time.Duration
+		wantOk   bool
+	}{
+		{0, false, 0, true},
+		{90, false, 90 * time.Second, true},
+		{86400, false, 24 * time.Hour, true},
+		{-1, false, 0, false}, // Not available.
+		{-2, false, 0, false}, // Unknown.
+		{90, true, 0, false},
+		{-1, true, 0, false},
+	} {
+		torrent := &transmission.Torrent{Eta: tc.eta, EtaIdle: tc.eta, IsFinished: tc.finished}
+		if got, ok := torrent.ETADuration(); got != tc.want || ok != tc.wantOk {
+			t.Errorf("eta %d, finished %v: ETADuration() = %v, %v; want %v, %v", tc.eta, tc.finished, got, ok, tc.want, tc.wantOk)
+		}
+		if got, ok := torrent.EtaIdleDuration(); got != tc.want || ok != tc.wantOk {
+			t.Errorf("etaIdle %d, finished %v: EtaIdleDuration() = %v, %v; want %v, %v", tc.eta, tc.finished, got, ok, tc.want, tc.wantOk)
+		}
+	}
+}
@@ -0,0 +1,1097 @@
+package transmission_go_api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+func newClient(t *testing.T, address string, opts ...transmission.Option) *transmission.Transmission {
+	t.Helper()
+	client, err := transmission.NewWithOptions(address, opts...)
+	if err != nil {
+		t.Fatalf("NewWithOptions: %v", err)
+	}
+	return client
+}
+
+// countMethod returns how many requests s received for method.
+func countMethod(s *transmissiontest.Server, method string) int {
+	n := 0
+	for _, req := range s.Requests() {
+		if req.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+func TestConcurrentListAllRefreshesSessionOnce(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.AddTorrent(transmission.Torrent{Name: "a"})
+	client := newClient(t, s.URL)
+
+	const calls = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ListAll(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("ListAll: %v", err)
+	}
+
+	if got := s.Sessions(); got != 1 {
+		t.Errorf("server handed out %d session ids, want 1", got)
+	}
+	// One session-get for the RPC version, refused once for the session id,
+	// then one torrent-get per call.
+	if got := countMethod(s, "session-get"); got != 2 {
+		t.Errorf("got %d session-get requests, want 2", got)
+	}
+	if got := countMethod(s, "torrent-get"); got != calls {
+		t.Errorf("got %d torrent-get requests, want %d", got, calls)
+	}
+	if id := client.SessionId(); id == "" {
+		t.Errorf("SessionId() is empty after the calls")
+	}
+}
+
+// removeArguments returns the arguments of the torrent-remove requests s
+// received.
+func removeArguments(t *testing.T, s *transmissiontest.Server) []map[string]interface{} {
+	t.Helper()
+	var args []map[string]interface{}
+	for _, req := range s.Requests() {
+		if req.Method != "torrent-remove" || req.Header.Get("X-Transmission-Session-Id") == "" {
+			continue
+		}
+		var arg map[string]interface{}
+		if err := json.Unmarshal(req.Arguments, &arg); err != nil {
+			t.Fatalf("torrent-remove arguments %s: %v", req.Arguments, err)
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+func TestRemoveKeepsData(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	id := s.AddTorrent(transmission.Torrent{Name: "a"})
+	client := newClient(t, s.URL)
+
+	if err := client.Remove([]int64{id}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	args := removeArguments(t, s)
+	if len(args) != 1 {
+		t.Fatalf("got %d torrent-remove requests, want 1", len(args))
+	}
+	if deleteData, ok := args[0]["delete-local-data"]; ok && deleteData != false {
+		t.Errorf("Remove sent delete-local-data = %v", deleteData)
+	}
+	if ids, _ := args[0]["ids"].([]interface{}); len(ids) != 1 || ids[0] != float64(id) {
+		t.Errorf("Remove sent ids %v, want [%d]", args[0]["ids"], id)
+	}
+}
+
+func TestRemoveWithDataDeletesData(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	id := s.AddTorrent(transmission.Torrent{Name: "a"})
+	client := newClient(t, s.URL)
+
+	if err := client.RemoveWithData([]int64{id}); err != nil {
+		t.Fatalf("RemoveWithData: %v", err)
+	}
+	args := removeArguments(t, s)
+	if len(args) != 1 {
+		t.Fatalf("got %d torrent-remove requests, want 1", len(args))
+	}
+	if deleteData := args[0]["delete-local-data"]; deleteData != true {
+		t.Errorf("RemoveWithData sent delete-local-data = %v, want true", deleteData)
+	}
+	if ids, _ := args[0]["ids"].([]interface{}); len(ids) != 1 || ids[0] != float64(id) {
+		t.Errorf("RemoveWithData sent ids %v, want [%d]", args[0]["ids"], id)
+	}
+}
+
+func TestRemoveWithoutIds(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.AddTorrent(transmission.Torrent{Name: "a"})
+	client := newClient(t, s.URL)
+
+	for name, remove := range map[string]func() error{
+		"Remove":                 func() error { return client.Remove(nil) },
+		"Remove empty":           func() error { return client.Remove([]int64{}) },
+		"RemoveWithData":         func() error { return client.RemoveWithData(nil) },
+		"RemoveWithData empty":   func() error { return client.RemoveWithData([]int64{}) },
+		"RemoveTorrentsWithData": func() error { return client.RemoveTorrentsWithData(nil) },
+	} {
+		if err := remove(); !errors.Is(err, transmission.ErrNoIds) {
+			t.Errorf("%s: got %v, want ErrNoIds", name, err)
+		}
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Errorf("server got %d requests, want none", n)
+	}
+	if n := len(s.Torrents()); n != 1 {
+		t.Errorf("%d torrents left, want 1", n)
+	}
+}
+
+func TestCancelSlowRequest(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetLatency(5 * time.Second)
+	client := newClient(t, s.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := client.GetSessionContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("GetSessionContext = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetSessionContext returned after %v", elapsed)
+	}
+}
+
+func TestSessionRetryChecksContext(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel once the 409 arrived, before the retry is sent.
+	client := newClient(t, s.URL, transmission.WithResponseInterceptor(func(resp *http.Response) error {
+		if resp.StatusCode == http.StatusConflict {
+			cancel()
+		}
+		return nil
+	}))
+
+	if _, err := client.GetSessionContext(ctx); err != context.Canceled {
+		t.Errorf("GetSessionContext = %v, want context.Canceled", err)
+	}
+	if got := client.Stats().Requests; got != 1 {
+		t.Errorf("client sent %d requests, want 1", got)
+	}
+	if got := len(s.Requests()); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+}
+
+// sentFields returns the fields argument of the last torrent-get s received.
+func sentFields(t *testing.T, s *transmissiontest.Server) map[string]bool {
+	t.Helper()
+	var last json.RawMessage
+	for _, req := range s.Requests() {
+		if req.Method == "torrent-get" {
+			last = req.Arguments
+		}
+	}
+	var args struct {
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal(last, &args); err != nil {
+		t.Fatalf("torrent-get arguments %s: %v", last, err)
+	}
+	fields := map[string]bool{}
+	for _, field := range args.Fields {
+		fields[field] = true
+	}
+	return fields
+}
+
+func TestListAllLightFields(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.AddTorrent(transmission.Torrent{Name: "a"})
+	client := newClient(t, s.URL)
+
+	if _, err := client.ListAll(); err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	all := sentFields(t, s)
+	if _, err := client.ListAllLight(); err != nil {
+		t.Fatalf("ListAllLight: %v", err)
+	}
+	light := sentFields(t, s)
+
+	heavy := []string{transmission.FieldFiles, transmission.FieldFileStats, transmission.FieldPieces}
+	for _, field := range heavy {
+		if !all[field] {
+			t.Errorf("ListAll did not request %q", field)
+		}
+		if light[field] {
+			t.Errorf("ListAllLight requested %q", field)
+		}
+	}
+	if len(light) != len(all)-len(heavy) {
+		t.Errorf("ListAllLight requested %d fields, want the %d of ListAll without the heavy ones", len(light), len(all)-len(heavy))
+	}
+	for field := range light {
+		if !all[field] {
+			t.Errorf("ListAllLight requested %q, which ListAll does not", field)
+		}
+	}
+}
+
+func TestListAllLightOldDaemon(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetSessionField("rpc-version", 15)
+	client := newClient(t, s.URL)
+
+	if _, err := client.ListAllLight(); err != nil {
+		t.Fatalf("ListAllLight: %v", err)
+	}
+	fields := sentFields(t, s)
+	// Added in RPC versions 16 and 17.
+	for _, field := range []string{transmission.FieldLabels, transmission.FieldGroup, transmission.FieldFileCount} {
+		if fields[field] {
+			t.Errorf("ListAllLight requested %q from an RPC 15 daemon", field)
+		}
+	}
+	if !fields[transmission.FieldName] || !fields[transmission.FieldStatus] {
+		t.Errorf("ListAllLight requested %v, want name and status", fields)
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.AddTorrent(transmission.Torrent{Name: "a"})
+	dialer := &countingDialer{}
+	client := newClient(t, s.URL, transmission.WithHTTPClient(&http.Client{
+		Transport: &http.Transport{DialContext: dialer.dial},
+	}))
+
+	for i := 0; i < 20; i++ {
+		if i%5 == 0 {
+			// Go through the 409 handshake again, whose body must be
+			// drained for the connection to be reused.
+			s.ExpireSession()
+		}
+		if _, err := client.ListAll(); err != nil {
+			t.Fatalf("ListAll %d: %v", i, err)
+		}
+	}
+	if n := dialer.count(); n != 1 {
+		t.Errorf("client opened %d connections, want 1", n)
+	}
+	if n := s.Sessions(); n != 4 {
+		t.Errorf("server handed out %d sessions, want 4", n)
+	}
+}
+
+func TestListByStatus(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.AddTorrent(transmission.Torrent{Name: "stopped", Status: transmission.TR_STATUS_STOPPED})
+	s.AddTorrent(transmission.Torrent{Name: "downloading", Status: transmission.TR_STATUS_DOWNLOAD})
+	s.AddTorrent(transmission.Torrent{Name: "seeding", Status: transmission.TR_STATUS_SEED})
+	s.AddTorrent(transmission.Torrent{Name: "queued", Status: transmission.TR_STATUS_DOWNLOAD_WAIT})
+	client := newClient(t, s.URL)
+
+	for _, tc := range []struct {
+		statuses []transmission.Status
+		want     []string
+	}{
+		{nil, nil},
+		{[]transmission.Status{transmission.TR_STATUS_STOPPED}, []string{"stopped"}},
+		{[]transmission.Status{transmission.TR_STATUS_DOWNLOAD, transmission.TR_STATUS_DOWNLOAD_WAIT}, []string{"downloading", "queued"}},
+		{[]transmission.Status{transmission.TR_STATUS_SEEK}, []string{"seeding"}},
+		{[]transmission.Status{transmission.TR_STATUS_CHECK}, nil},
+	} {
+		torrents, err := client.ListByStatus(tc.statuses...)
+		if err != nil {
+			t.Fatalf("ListByStatus(%v): %v", tc.statuses, err)
+		}
+		var names []string
+		for _, torrent := range torrents {
+			names = append(names, torrent.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("ListByStatus(%v) = %v, want %v", tc.statuses, names, tc.want)
+		}
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	for status, want := range map[transmission.Status]string{
+		transmission.TR_STATUS_STOPPED:       "stopped",
+		transmission.TR_STATUS_CHECK_WAIT:    "queued to verify",
+		transmission.TR_STATUS_CHECK:         "verifying",
+		transmission.TR_STATUS_DOWNLOAD_WAIT: "queued to download",
+		transmission.TR_STATUS_DOWNLOAD:      "downloading",
+		transmission.TR_STATUS_SEED_WAIT:     "queued to seed",
+		transmission.TR_STATUS_SEED:          "seeding",
+		7:                                    "Status(7)",
+	} {
+		if got := status.String(); got != want {
+			t.Errorf("Status(%d).String() = %q, want %q", int64(status), got, want)
+		}
+	}
+}
+
+// sentArguments returns the arguments of the last method request s accepted,
+// re-encoded with sorted keys so that they can be compared as strings.
+func sentArguments(t *testing.T, s *transmissiontest.Server, method string) string {
+	t.Helper()
+	requests := s.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		req := requests[i]
+		if req.Method != method || req.Header.Get("X-Transmission-Session-Id") == "" {
+			continue
+		}
+		return canonicalJSON(t, string(req.Arguments))
+	}
+	t.Fatalf("server got no %s request", method)
+	return ""
+}
+
+// canonicalJSON re-encodes data with sorted keys.
+func canonicalJSON(t *testing.T, data string) string {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(canonical)
+}
+
+// newSetServer returns a fake daemon accepting torrent-set.
+func newSetServer() *transmissiontest.Server {
+	s := transmissiontest.NewServer()
+	s.SetResult("torrent-set", "success")
+	return s
+}
+
+// argumentsTest is a call and the arguments it should send.
+type argumentsTest struct {
+	name string
+	call func() error
+	want string
+}
+
+// checkArguments makes the calls of tests and checks the arguments of the
+// method request each sent.
+func checkArguments(t *testing.T, s *transmissiontest.Server, method string, tests []argumentsTest) {
+	t.Helper()
+	for _, tc := range tests {
+		before := len(s.Requests())
+		if err := tc.call(); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if len(s.Requests()) == before {
+			t.Errorf("%s sent no request", tc.name)
+			continue
+		}
+		if got, want := sentArguments(t, s, method), canonicalJSON(t, tc.want); got != want {
+			t.Errorf("%s sent %s, want %s", tc.name, got, want)
+		}
+	}
+}
+
+func TestSetSpeedLimits(t *testing.T) {
+	s := newSetServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	checkArguments(t, s, "torrent-set", []argumentsTest{
+		{
+			"SetDownloadLimit",
+			func() error { return client.SetDownloadLimit([]int64{1, 2}, 500, true) },
+			`{"ids":[1,2],"downloadLimit":500,"downloadLimited":true}`,
+		},
+		{
+			"SetDownloadLimit disabled",
+			func() error { return client.SetDownloadLimit([]int64{1}, 0, false) },
+			`{"ids":[1],"downloadLimit":0,"downloadLimited":false}`,
+		},
+		{
+			"SetUploadLimit",
+			func() error { return client.SetUploadLimit([]int64{3}, 50, true) },
+			`{"ids":[3],"uploadLimit":50,"uploadLimited":true}`,
+		},
+		{
+			"SetUploadLimit disabled",
+			func() error { return client.SetUploadLimit([]int64{3}, 0, false) },
+			`{"ids":[3],"uploadLimit":0,"uploadLimited":false}`,
+		},
+		{
+			"SetSpeedLimits",
+			func() error { return client.SetSpeedLimits([]int64{4}, 0, false, 20, true) },
+			`{"ids":[4],"downloadLimit":0,"downloadLimited":false,"uploadLimit":20,"uploadLimited":true}`,
+		},
+		{
+			"SetSpeedLimitsTorrents",
+			func() error {
+				return client.SetSpeedLimitsTorrents([]*transmission.Torrent{{Id: 5}, {Id: 6}}, 100, true, 0, false)
+			},
+			`{"ids":[5,6],"downloadLimit":100,"downloadLimited":true,"uploadLimit":0,"uploadLimited":false}`,
+		},
+	})
+}
+
+func TestSetBandwidthPriority(t *testing.T) {
+	s := newSetServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	checkArguments(t, s, "torrent-set", []argumentsTest{
+		{
+			"PriorityLow",
+			func() error { return client.SetBandwidthPriority([]int64{1}, transmission.PriorityLow) },
+			`{"ids":[1],"bandwidthPriority":-1}`,
+		},
+		{
+			"PriorityNormal",
+			func() error { return client.SetBandwidthPriority([]int64{1, 2}, transmission.PriorityNormal) },
+			`{"ids":[1,2],"bandwidthPriority":0}`,
+		},
+		{
+			"PriorityHigh",
+			func() error {
+				return client.SetBandwidthPriorityTorrents([]*transmission.Torrent{{Id: 3}}, transmission.PriorityHigh)
+			},
+			`{"ids":[3],"bandwidthPriority":1}`,
+		},
+	})
+
+	before := len(s.Requests())
+	for _, priority := range []transmission.Priority{-2, 2} {
+		if err := client.SetBandwidthPriority([]int64{1}, priority); err == nil {
+			t.Errorf("SetBandwidthPriority accepted priority %d", priority)
+		}
+	}
+	if n := len(s.Requests()) - before; n != 0 {
+		t.Errorf("server got %d requests for invalid priorities, want 0", n)
+	}
+}
+
+func TestSetHonorsSessionLimitsAndPeerLimit(t *testing.T) {
+	s := newSetServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	torrents := []*transmission.Torrent{{Id: 7}, {Id: 8}}
+
+	checkArguments(t, s, "torrent-set", []argumentsTest{
+		{
+			"SetHonorsSessionLimits true",
+			func() error { return client.SetHonorsSessionLimits([]int64{1}, true) },
+			`{"ids":[1],"honorsSessionLimits":true}`,
+		},
+		{
+			"SetHonorsSessionLimits false",
+			func() error { return client.SetHonorsSessionLimits([]int64{1}, false) },
+			`{"ids":[1],"honorsSessionLimits":false}`,
+		},
+		{
+			"SetHonorsSessionLimitsTorrents",
+			func() error { return client.SetHonorsSessionLimitsTorrents(torrents, false) },
+			`{"ids":[7,8],"honorsSessionLimits":false}`,
+		},
+		{
+			"SetPeerLimit",
+			func() error { return client.SetPeerLimit([]int64{2}, 60) },
+			`{"ids":[2],"peer-limit":60}`,
+		},
+		{
+			"SetPeerLimit 0",
+			func() error { return client.SetPeerLimit([]int64{2}, 0) },
+			`{"ids":[2],"peer-limit":0}`,
+		},
+		{
+			"SetPeerLimitTorrents",
+			func() error { return client.SetPeerLimitTorrents(torrents, 0) },
+			`{"ids":[7,8],"peer-limit":0}`,
+		},
+	})
+
+	if err := client.SetPeerLimit([]int64{2}, -1); err == nil {
+		t.Error("SetPeerLimit accepted a negative limit")
+	}
+	if err := client.SetHonorsSessionLimitsTorrents(nil, true); !errors.Is(err, transmission.ErrNoIds) {
+		t.Errorf("SetHonorsSessionLimitsTorrents(nil) = %v, want ErrNoIds", err)
+	}
+}
+
+func TestSetTorrents(t *testing.T) {
+	s := newSetServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	no := false
+	zero := int64(0)
+	ratio := 0.0
+	mode := transmission.SeedRatioModeGlobal
+	empty := ""
+
+	checkArguments(t, s, "torrent-set", []argumentsTest{
+		{
+			"no fields",
+			func() error { return client.SetTorrents([]int64{1}, &transmission.TorrentSetArgs{}) },
+			`{"ids":[1]}`,
+		},
+		{
+			"zero values",
+			func() error {
+				return client.SetTorrents([]int64{1, 2}, &transmission.TorrentSetArgs{
+					DownloadLimited:     &no,
+					HonorsSessionLimits: &no,
+					QueuePosition:       &zero,
+					SeedRatioLimit:      &ratio,
+					SeedRatioMode:       &mode,
+					TrackerList:         &empty,
+					Labels:              &[]string{},
+					FilesWanted:         &[]int64{},
+				})
+			},
+			`{"ids":[1,2],"downloadLimited":false,"honorsSessionLimits":false,"queuePosition":0,
+			  "seedRatioLimit":0,"seedRatioMode":0,"trackerList":"","labels":[],"files-wanted":[]}`,
+		},
+	})
+
+	// Every pointer field is sent once set, whatever its value.
+	var args transmission.TorrentSetArgs
+	v := reflect.ValueOf(&args).Elem()
+	var want []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Ptr {
+			continue
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+		want = append(want, strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0])
+	}
+	if err := client.SetTorrents([]int64{1}, &args); err != nil {
+		t.Fatalf("SetTorrents: %v", err)
+	}
+	var sent map[string]interface{}
+	if err := json.Unmarshal([]byte(sentArguments(t, s, "torrent-set")), &sent); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range want {
+		if _, ok := sent[name]; !ok {
+			t.Errorf("SetTorrents did not send the zero %s", name)
+		}
+	}
+	if len(sent) != len(want)+1 {
+		t.Errorf("SetTorrents sent %d arguments, want the %d set fields and ids", len(sent), len(want))
+	}
+
+	if err := client.SetTorrents(nil, &transmission.TorrentSetArgs{}); !errors.Is(err, transmission.ErrNoIds) {
+		t.Errorf("SetTorrents(nil) = %v, want ErrNoIds", err)
+	}
+	if err := client.SetTorrents([]int64{1}, nil); err == nil {
+		t.Error("SetTorrents accepted nil arguments")
+	}
+}
+
+// torrentFile is a small .torrent file. Its pieces base64 encode to "+/++",
+// which would not survive a URL-safe encoding.
+var torrentFile = []byte("d8:announce35:http://tracker.example.com/announce4:infod6:lengthi1e4:name5:a.txt6:pieces3:\xfb\xff\xbeee")
+
+// sentMetainfo returns the decoded metainfo of the last torrent-add request.
+func sentMetainfo(t *testing.T, s *transmissiontest.Server) []byte {
+	t.Helper()
+	var args struct {
+		Metainfo string `json:"metainfo"`
+	}
+	if err := json.Unmarshal([]byte(sentArguments(t, s, "torrent-add")), &args); err != nil {
+		t.Fatal(err)
+	}
+	metainfo, err := base64.StdEncoding.DecodeString(args.Metainfo)
+	if err != nil {
+		t.Fatalf("metainfo %q is not base64: %v", args.Metainfo, err)
+	}
+	return metainfo
+}
+
+func TestAddTorrentFile(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	path := filepath.Join(t.TempDir(), "a.torrent")
+	if err := os.WriteFile(path, torrentFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.AddTorrentFile(path)
+	if err != nil {
+		t.Fatalf("AddTorrentFile: %v", err)
+	}
+	if res.Torrent == nil || res.Torrent.Id == 0 || res.Duplicate {
+		t.Errorf("AddTorrentFile = %+v, want a new torrent", res)
+	}
+	if metainfo := sentMetainfo(t, s); !bytes.Equal(metainfo, torrentFile) {
+		t.Errorf("AddTorrentFile sent metainfo %q, want %q", metainfo, torrentFile)
+	}
+
+	s.Close()
+	s = transmissiontest.NewServer()
+	defer s.Close()
+	client = newClient(t, s.URL)
+	if _, err := client.AddTorrentBytes(torrentFile); err != nil {
+		t.Fatalf("AddTorrentBytes: %v", err)
+	}
+	if metainfo := sentMetainfo(t, s); !bytes.Equal(metainfo, torrentFile) {
+		t.Errorf("AddTorrentBytes sent metainfo %q, want %q", metainfo, torrentFile)
+	}
+}
+
+func TestAddTorrentFileErrors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	if _, err := client.AddTorrentFile(filepath.Join(t.TempDir(), "missing.torrent")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("AddTorrentFile of a missing file = %v, want ErrNotExist", err)
+	}
+	if _, err := client.AddTorrentBytes(nil); err == nil {
+		t.Error("AddTorrentBytes accepted empty metainfo")
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Errorf("server got %d requests, want 0", n)
+	}
+
+	s.SetResult("torrent-add", "invalid or corrupt torrent file")
+	var rpcErr *transmission.RPCError
+	if _, err := client.AddTorrentBytes(torrentFile); !errors.As(err, &rpcErr) || rpcErr.Result != "invalid or corrupt torrent file" {
+		t.Errorf("AddTorrentBytes = %v, want the daemon's RPCError", err)
+	}
+}
+
+func TestAddTorrentURL(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	var userAgents []string
+	var mu sync.Mutex
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		mu.Unlock()
+		if r.URL.Path == "/old.torrent" {
+			http.Redirect(w, r, "/a.torrent", http.StatusFound)
+			return
+		}
+		w.Write(torrentFile)
+	}))
+	defer files.Close()
+	dialer := &countingDialer{}
+	client := newClient(t, s.URL, transmission.WithDialContext(dialer.dial), transmission.WithUserAgent("fetcher/1.0"))
+
+	res, err := client.AddTorrentURL(context.Background(), files.URL+"/old.torrent", nil)
+	if err != nil {
+		t.Fatalf("AddTorrentURL: %v", err)
+	}
+	if res.Torrent == nil || res.Torrent.Id == 0 {
+		t.Errorf("AddTorrentURL = %+v, want a new torrent", res)
+	}
+	if metainfo := sentMetainfo(t, s); !bytes.Equal(metainfo, torrentFile) {
+		t.Errorf("AddTorrentURL sent metainfo %q, want %q", metainfo, torrentFile)
+	}
+	if want := []string{"fetcher/1.0", "fetcher/1.0"}; !reflect.DeepEqual(userAgents, want) {
+		t.Errorf("file server saw User-Agents %q, want %q", userAgents, want)
+	}
+	// One connection to the file server, one to the daemon.
+	if n := dialer.count(); n != 2 {
+		t.Errorf("client dialed %d connections, want 2", n)
+	}
+
+	// FetchHeader overrides the User-Agent, FetchClient the http.Client.
+	other := &countingDialer{}
+	opts := &transmission.AddOptions{
+		FetchHeader: http.Header{"User-Agent": {"custom"}},
+		FetchClient: &http.Client{Transport: &http.Transport{DialContext: other.dial}},
+	}
+	if _, err := client.AddTorrentURL(context.Background(), files.URL+"/a.torrent", opts); err != nil {
+		t.Fatalf("AddTorrentURL with FetchClient: %v", err)
+	}
+	if got := userAgents[len(userAgents)-1]; got != "custom" {
+		t.Errorf("file server saw User-Agent %q, want custom", got)
+	}
+	if n := other.count(); n != 1 {
+		t.Errorf("FetchClient dialed %d connections, want 1", n)
+	}
+}
+
+func TestAddTorrentURLErrors(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large.torrent":
+			// A valid looking dictionary, one byte over the limit.
+			body := bytes.Repeat([]byte("x"), 10<<20+1)
+			body[0], body[len(body)-1] = 'd', 'e'
+			w.Write(body)
+		case "/page.html":
+			w.Write([]byte("<html>not a torrent</html>"))
+		case "/empty.torrent":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer files.Close()
+	client := newClient(t, s.URL)
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"/large.torrent", "larger than"},
+		{"/page.html", "not a bencoded torrent file"},
+		{"/empty.torrent", "not a bencoded torrent file"},
+		{"/missing.torrent", "404"},
+	} {
+		_, err := client.AddTorrentURL(context.Background(), files.URL+tc.path, nil)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("AddTorrentURL(%s) = %v, want an error containing %q", tc.path, err, tc.want)
+		}
+	}
+	if n := countMethod(s, "torrent-add"); n != 0 {
+		t.Errorf("failed downloads sent %d torrent-add requests", n)
+	}
+}
+
+func TestAddWithOptionsFiles(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	paused := false
+	metainfo := base64.StdEncoding.EncodeToString(torrentFile)
+
+	checkArguments(t, s, "torrent-add", []argumentsTest{
+		{
+			"no options",
+			func() error {
+				_, err := client.AddTorrentBytesWithOptions(torrentFile, &transmission.AddOptions{})
+				return err
+			},
+			`{"metainfo":"` + metainfo + `"}`,
+		},
+		{
+			"empty file lists",
+			func() error {
+				_, err := client.AddWithOptions("/torrents/b.torrent", &transmission.AddOptions{
+					Paused:        &paused,
+					FilesWanted:   []int64{},
+					PriorityHigh:  []int64{},
+					FilesUnwanted: nil,
+				})
+				return err
+			},
+			`{"filename":"/torrents/b.torrent","paused":false}`,
+		},
+		{
+			"file lists",
+			func() error {
+				_, err := client.AddWithOptions("/torrents/c.torrent", &transmission.AddOptions{
+					FilesWanted:    []int64{0, 2, 5},
+					FilesUnwanted:  []int64{1, 3, 4},
+					PriorityHigh:   []int64{5},
+					PriorityNormal: []int64{0},
+					PriorityLow:    []int64{2},
+				})
+				return err
+			},
+			`{"filename":"/torrents/c.torrent","files-wanted":[0,2,5],"files-unwanted":[1,3,4],
+			  "priority-high":[5],"priority-normal":[0],"priority-low":[2]}`,
+		},
+	})
+}
+
+func TestAddDuplicate(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	added, err := client.AddTorrentBytes(torrentFile)
+	if err != nil {
+		t.Fatalf("AddTorrentBytes: %v", err)
+	}
+	if added.Duplicate || added.Torrent == nil || added.Torrent.Id == 0 || added.Torrent.HashString == "" {
+		t.Fatalf("first AddTorrentBytes = %+v, want a new torrent", added)
+	}
+	duplicate, err := client.AddTorrentBytes(torrentFile)
+	if err != nil {
+		t.Fatalf("AddTorrentBytes: %v", err)
+	}
+	if !duplicate.Duplicate || duplicate.Torrent == nil {
+		t.Fatalf("second AddTorrentBytes = %+v, want a duplicate", duplicate)
+	}
+	if !reflect.DeepEqual(duplicate.Torrent, added.Torrent) {
+		t.Errorf("duplicate torrent %+v, want the added %+v", duplicate.Torrent, added.Torrent)
+	}
+	if n := len(s.Torrents()); n != 1 {
+		t.Errorf("server has %d torrents, want 1", n)
+	}
+}
+
+func TestAddResponses(t *testing.T) {
+	for _, tc := range []struct {
+		arguments     string
+		wantDuplicate bool
+		wantErr       bool
+	}{
+		{`{"torrent-added":{"hashString":"c9a337562cb0360fd6f5ab40fd2b6b81c5325dbd","id":12,"name":"a.txt"}}`, false, false},
+		{`{"torrent-duplicate":{"hashString":"c9a337562cb0360fd6f5ab40fd2b6b81c5325dbd","id":12,"name":"a.txt"}}`, true, false},
+		{`{}`, false, true},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Tag int `json:"tag"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			fmt.Fprintf(w, `{"result":"success","arguments":%s,"tag":%d}`, tc.arguments, req.Tag)
+		}))
+		client := newClient(t, server.URL)
+		res, err := client.Add("magnet:?xt=urn:btih:c9a337562cb0360fd6f5ab40fd2b6b81c5325dbd")
+		server.Close()
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: Add = %+v, want an error", tc.arguments, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Add: %v", tc.arguments, err)
+			continue
+		}
+		want := &transmission.Torrent{Id: 12, Name: "a.txt", HashString: "c9a337562cb0360fd6f5ab40fd2b6b81c5325dbd"}
+		if res.Duplicate != tc.wantDuplicate || !reflect.DeepEqual(res.Torrent, want) {
+			t.Errorf("%s: Add = %+v, want Duplicate %v and %+v", tc.arguments, res, tc.wantDuplicate, want)
+		}
+	}
+}
+
+func TestSetLocation(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.SetResult("torrent-set-location", "success")
+	client := newClient(t, s.URL)
+
+	checkArguments(t, s, "torrent-set-location", []argumentsTest{
+		{
+			"move",
+			func() error { return client.SetLocation([]int64{1, 2}, "/mnt/disk2/movies", true) },
+			`{"ids":[1,2],"location":"/mnt/disk2/movies","move":true}`,
+		},
+		{
+			"find",
+			func() error { return client.SetLocation([]int64{3}, "/mnt/disk2/movies", false) },
+			`{"ids":[3],"location":"/mnt/disk2/movies","move":false}`,
+		},
+		{
+			"SetLocationTorrents",
+			func() error {
+				return client.SetLocationTorrents([]*transmission.Torrent{{Id: 4}, {Id: 5}}, "/downloads", false)
+			},
+			`{"ids":[4,5],"location":"/downloads","move":false}`,
+		},
+	})
+
+	before := len(s.Requests())
+	if err := client.SetLocation([]int64{1}, "", true); err == nil {
+		t.Error("SetLocation accepted an empty location")
+	}
+	if err := client.SetLocation(nil, "/downloads", true); !errors.Is(err, transmission.ErrNoIds) {
+		t.Errorf("SetLocation(nil) = %v, want ErrNoIds", err)
+	}
+	if n := len(s.Requests()) - before; n != 0 {
+		t.Errorf("server got %d requests for invalid calls, want 0", n)
+	}
+}
+
+func TestSetFilesWanted(t *testing.T) {
+	s := newSetServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	checkArguments(t, s, "torrent-set", []argumentsTest{
+		{
+			"SetFilesWanted",
+			func() error { return client.SetFilesWanted(1, []int64{4, 0, 2}) },
+			`{"ids":[1],"files-wanted":[4,0,2]}`,
+		},
+		{
+			"SetFilesUnwanted",
+			func() error { return client.SetFilesUnwanted(1, []int64{0}) },
+			`{"ids":[1],"files-unwanted":[0]}`,
+		},
+		{
+			"SetAllFilesWanted true",
+			func() error { return client.SetAllFilesWanted(1, true) },
+			`{"ids":[1],"files-wanted":[]}`,
+		},
+		{
+			"SetAllFilesWanted false",
+			func() error { return client.SetAllFilesWanted(1, false) },
+			`{"ids":[1],"files-unwanted":[]}`,
+		},
+	})
+
+	// An empty list would mean all files.
+	if err := client.SetFilesWanted(1, nil); err == nil {
+		t.Error("SetFilesWanted accepted no file indices")
+	}
+	if err := client.SetFilesUnwanted(1, []int64{}); err == nil {
+		t.Error("SetFilesUnwanted accepted no file indices")
+	}
+}
+
+func TestTrackerSetters(t *testing.T) {
+	s := newSetServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	checkArguments(t, s, "torrent-set", []argumentsTest{
+		{
+			"AddTrackers",
+			func() error {
+				return client.AddTrackers([]int64{1, 2}, []string{"https://tracker.example.com/announce", "udp://tracker.example.org:6969"})
+			},
+			`{"ids":[1,2],"trackerAdd":["https://tracker.example.com/announce","udp://tracker.example.org:6969"]}`,
+		},
+		{
+			"RemoveTrackers",
+			func() error { return client.RemoveTrackers([]int64{1}, []int64{0, 3}) },
+			`{"ids":[1],"trackerRemove":[0,3]}`,
+		},
+		{
+			"ReplaceTracker",
+			func() error { return client.ReplaceTracker([]int64{1}, 0, "http://tracker.example.net/announce") },
+			`{"ids":[1],"trackerReplace":[0,"http://tracker.example.net/announce"]}`,
+		},
+	})
+
+	before := len(s.Requests())
+	for name, call := range map[string]func() error{
+		"AddTrackers without URLs":         func() error { return client.AddTrackers([]int64{1}, nil) },
+		"AddTrackers with a bad URL":       func() error { return client.AddTrackers([]int64{1}, []string{"tracker.example.com"}) },
+		"RemoveTrackers without ids":       func() error { return client.RemoveTrackers([]int64{1}, nil) },
+		"ReplaceTracker with a bad scheme": func() error { return client.ReplaceTracker([]int64{1}, 0, "ftp://tracker.example.com") },
+	} {
+		if err := call(); err == nil {
+			t.Errorf("%s succeeded", name)
+		}
+	}
+	if n := len(s.Requests()) - before; n != 0 {
+		t.Errorf("server got %d requests for invalid calls, want 0", n)
+	}
+}
+
+func TestQueueMoveOrder(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+	ids := []int64{9, 3, 7}
+	torrents := []*transmission.Torrent{{Id: 9}, {Id: 3}, {Id: 7}}
+
+	for method, moves := range map[string][]func() error{
+		"queue-move-up": {
+			func() error { return client.QueueMoveUp(ids) },
+			func() error { return client.QueueMoveUpTorrents(torrents) },
+		},
+		"queue-move-down": {
+			func() error { return client.QueueMoveDown(ids) },
+			func() error { return client.QueueMoveDownTorrents(torrents) },
+		},
+		"queue-move-top": {
+			func() error { return client.QueueMoveTop(ids) },
+		},
+		"queue-move-bottom": {
+			func() error { return client.QueueMoveBottom(ids) },
+		},
+	} {
+		s.SetResult(method, "success")
+		for _, move := range moves {
+			checkArguments(t, s, method, []argumentsTest{{method, move, `{"ids":[9,3,7]}`}})
+		}
+	}
+
+	before := len(s.Requests())
+	if err := client.QueueMoveUp(nil); !errors.Is(err, transmission.ErrNoIds) {
+		t.Errorf("QueueMoveUp(nil) = %v, want ErrNoIds", err)
+	}
+	if err := client.QueueMoveDownTorrents(nil); !errors.Is(err, transmission.ErrNoIds) {
+		t.Errorf("QueueMoveDownTorrents(nil) = %v, want ErrNoIds", err)
+	}
+	if n := len(s.Requests()) - before; n != 0 {
+		t.Errorf("server got %d requests without ids, want 0", n)
+	}
+}
+
+func TestGetRecentlyActive(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	a := s.AddTorrent(transmission.Torrent{Name: "a"})
+	b := s.AddTorrent(transmission.Torrent{Name: "b"})
+	client := newClient(t, s.URL)
+	if err := client.Remove([]int64{b}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	torrents, removed, err := client.GetRecentlyActive([]string{transmission.FieldName})
+	if err != nil {
+		t.Fatalf("GetRecentlyActive: %v", err)
+	}
+	if len(torrents) != 1 || torrents[0].Id != a || torrents[0].Name != "a" {
+		t.Errorf("GetRecentlyActive returned torrents %+v, want only %d", torrents, a)
+	}
+	if !reflect.DeepEqual(removed, []int64{b}) {
+		t.Errorf("GetRecentlyActive returned removed %v, want [%d]", removed, b)
+	}
+	if fields := sentFields(t, s); !fields[transmission.FieldId] || !fields[transmission.FieldName] || len(fields) != 2 {
+		t.Errorf("GetRecentlyActive requested fields %v, want id and name", fields)
+	}
+}
+
+func TestGetRecentlyActiveInvalidFields(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s.URL)
+
+	for _, fields := range [][]string{nil, {"nmae"}} {
+		if _, _, err := client.GetRecentlyActive(fields); err == nil {
+			t.Errorf("GetRecentlyActive(%q) succeeded", fields)
+		}
+	}
+	if n := countMethod(s, "torrent-get"); n != 0 {
+		t.Errorf("invalid fields sent %d torrent-get requests", n)
+	}
+}
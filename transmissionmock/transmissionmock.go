@@ -0,0 +1,258 @@
+// Package transmissionmock provides a fake transmission_go_api.Client for
+// tests that should not need a daemon.
+package transmissionmock
+
+import (
+	"sync"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+)
+
+// Call records one method call on a Client.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Client is a fake transmission_go_api.Client. Each method records its call
+// and returns what the matching Func field returns, or zero values and a nil
+// error if the field is nil. It is safe for concurrent use, but the Func
+// fields must be set before it is shared.
+type Client struct {
+	ListAllFunc         func() ([]*transmission.Torrent, error)
+	ListBriefFunc       func() ([]*transmission.Torrent, error)
+	GetFunc             func(ids []int64) ([]*transmission.Torrent, error)
+	GetTorrentFunc      func(id int64) (*transmission.Torrent, error)
+	GetWithFieldsFunc   func(ids []int64, fields []string) ([]*transmission.Torrent, error)
+	StartFunc           func(ids []int64) error
+	StartNowFunc        func(ids []int64) error
+	StopFunc            func(ids []int64) error
+	VerifyFunc          func(ids []int64) error
+	ReannounceFunc      func(ids []int64) error
+	RemoveFunc          func(ids []int64) error
+	RemoveWithDataFunc  func(ids []int64) error
+	AddFunc             func(source string) (*transmission.AddResult, error)
+	AddWithOptionsFunc  func(source string, opts *transmission.AddOptions) (*transmission.AddResult, error)
+	AddTorrentFileFunc  func(path string) (*transmission.AddResult, error)
+	SetTorrentsFunc     func(ids []int64, args *transmission.TorrentSetArgs) error
+	SetLocationFunc     func(ids []int64, location string, move bool) error
+	RenamePathFunc      func(id int64, path string, name string) (*transmission.RenameResult, error)
+	GetSessionFunc      func() (*transmission.Session, error)
+	SetSessionFunc      func(args *transmission.SessionSetArgs) error
+	GetSessionStatsFunc func() (*transmission.SessionStats, error)
+	FreeSpaceFunc       func(path string) (int64, error)
+	RPCVersionFunc      func() (int64, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ transmission.Client = (*Client)(nil)
+
+// Calls returns the calls made so far, oldest first.
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// CallsTo returns the calls made so far to the named method.
+func (c *Client) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range c.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (c *Client) record(method string, args ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+}
+
+func (c *Client) ListAll() ([]*transmission.Torrent, error) {
+	c.record("ListAll")
+	if c.ListAllFunc == nil {
+		return nil, nil
+	}
+	return c.ListAllFunc()
+}
+
+func (c *Client) ListBrief() ([]*transmission.Torrent, error) {
+	c.record("ListBrief")
+	if c.ListBriefFunc == nil {
+		return nil, nil
+	}
+	return c.ListBriefFunc()
+}
+
+func (c *Client) Get(ids []int64) ([]*transmission.Torrent, error) {
+	c.record("Get", ids)
+	if c.GetFunc == nil {
+		return nil, nil
+	}
+	return c.GetFunc(ids)
+}
+
+func (c *Client) GetTorrent(id int64) (*transmission.Torrent, error) {
+	c.record("GetTorrent", id)
+	if c.GetTorrentFunc == nil {
+		return nil, nil
+	}
+	return c.GetTorrentFunc(id)
+}
+
+func (c *Client) GetWithFields(ids []int64, fields []string) ([]*transmission.Torrent, error) {
+	c.record("GetWithFields", ids, fields)
+	if c.GetWithFieldsFunc == nil {
+		return nil, nil
+	}
+	return c.GetWithFieldsFunc(ids, fields)
+}
+
+func (c *Client) Start(ids []int64) error {
+	c.record("Start", ids)
+	if c.StartFunc == nil {
+		return nil
+	}
+	return c.StartFunc(ids)
+}
+
+func (c *Client) StartNow(ids []int64) error {
+	c.record("StartNow", ids)
+	if c.StartNowFunc == nil {
+		return nil
+	}
+	return c.StartNowFunc(ids)
+}
+
+func (c *Client) Stop(ids []int64) error {
+	c.record("Stop", ids)
+	if c.StopFunc == nil {
+		return nil
+	}
+	return c.StopFunc(ids)
+}
+
+func (c *Client) Verify(ids []int64) error {
+	c.record("Verify", ids)
+	if c.VerifyFunc == nil {
+		return nil
+	}
+	return c.VerifyFunc(ids)
+}
+
+func (c *Client) Reannounce(ids []int64) error {
+	c.record("Reannounce", ids)
+	if c.ReannounceFunc == nil {
+		return nil
+	}
+	return c.ReannounceFunc(ids)
+}
+
+func (c *Client) Remove(ids []int64) error {
+	c.record("Remove", ids)
+	if c.RemoveFunc == nil {
+		return nil
+	}
+	return c.RemoveFunc(ids)
+}
+
+func (c *Client) RemoveWithData(ids []int64) error {
+	c.record("RemoveWithData", ids)
+	if c.RemoveWithDataFunc == nil {
+		return nil
+	}
+	return c.RemoveWithDataFunc(ids)
+}
+
+func (c *Client) Add(source string) (*transmission.AddResult, error) {
+	c.record("Add", source)
+	if c.AddFunc == nil {
+		return nil, nil
+	}
+	return c.AddFunc(source)
+}
+
+func (c *Client) AddWithOptions(source string, opts *transmission.AddOptions) (*transmission.AddResult, error) {
+	c.record("AddWithOptions", source, opts)
+	if c.AddWithOptionsFunc == nil {
+		return nil, nil
+	}
+	return c.AddWithOptionsFunc(source, opts)
+}
+
+func (c *Client) AddTorrentFile(path string) (*transmission.AddResult, error) {
+	c.record("AddTorrentFile", path)
+	if c.AddTorrentFileFunc == nil {
+		return nil, nil
+	}
+	return c.AddTorrentFileFunc(path)
+}
+
+func (c *Client) SetTorrents(ids []int64, args *transmission.TorrentSetArgs) error {
+	c.record("SetTorrents", ids, args)
+	if c.SetTorrentsFunc == nil {
+		return nil
+	}
+	return c.SetTorrentsFunc(ids, args)
+}
+
+func (c *Client) SetLocation(ids []int64, location string, move bool) error {
+	c.record("SetLocation", ids, location, move)
+	if c.SetLocationFunc == nil {
+		return nil
+	}
+	return c.SetLocationFunc(ids, location, move)
+}
+
+func (c *Client) RenamePath(id int64, path string, name string) (*transmission.RenameResult, error) {
+	c.record("RenamePath", id, path, name)
+	if c.RenamePathFunc == nil {
+		return nil, nil
+	}
+	return c.RenamePathFunc(id, path, name)
+}
+
+func (c *Client) GetSession() (*transmission.Session, error) {
+	c.record("GetSession")
+	if c.GetSessionFunc == nil {
+		return nil, nil
+	}
+	return c.GetSessionFunc()
+}
+
+func (c *Client) SetSession(args *transmission.SessionSetArgs) error {
+	c.record("SetSession", args)
+	if c.SetSessionFunc == nil {
+		return nil
+	}
+	return c.SetSessionFunc(args)
+}
+
+func (c *Client) GetSessionStats() (*transmission.SessionStats, error) {
+	c.record("GetSessionStats")
+	if c.GetSessionStatsFunc == nil {
+		return nil, nil
+	}
+	return c.GetSessionStatsFunc()
+}
+
+func (c *Client) FreeSpace(path string) (int64, error) {
+	c.record("FreeSpace", path)
+	if c.FreeSpaceFunc == nil {
+		return 0, nil
+	}
+	return c.FreeSpaceFunc(path)
+}
+
+func (c *Client) RPCVersion() (int64, error) {
+	c.record("RPCVersion")
+	if c.RPCVersionFunc == nil {
+		return 0, nil
+	}
+	return c.RPCVersionFunc()
+}
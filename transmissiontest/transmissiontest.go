@@ -0,0 +1,468 @@
+// Package transmissiontest provides a fake Transmission daemon for tests,
+// speaking enough of the RPC protocol over HTTP to exercise real clients,
+// including the session id handshake.
+package transmissiontest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+)
+
+const csrfSessionHeader = "X-Transmission-Session-Id"
+
+// Server is a fake daemon holding its torrents in memory. It implements
+// torrent-get, torrent-add, torrent-remove, torrent-start, torrent-stop,
+// torrent-set, session-get, session-set and blocklist-update, answering other
+// methods with an error result. A torrent-get for "recently-active" also lists
+// the torrents removed in the last minute.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	sessionId string
+	sessions  int
+	torrents  map[int64]*transmission.Torrent
+	nextId    int64
+	latency   time.Duration
+	failures  []failure
+	results   map[string]string
+	session   map[string]interface{}
+	requests  []Request
+	// removed maps the ids of removed torrents to their removal time.
+	removed map[int64]time.Time
+}
+
+// Request is an HTTP request the server received.
+type Request struct {
+	Header http.Header
+	// Method and Arguments are empty if the body is not an RPC request.
+	Method    string
+	Arguments json.RawMessage
+}
+
+type failure struct {
+	status int
+	body   string
+}
+
+// NewServer starts a fake daemon with no torrents. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		torrents: map[int64]*transmission.Torrent{},
+		nextId:   1,
+		results:  map[string]string{},
+		removed:  map[int64]time.Time{},
+		session: map[string]interface{}{
+			"rpc-version":         17,
+			"rpc-version-minimum": 14,
+			"version":             "4.0.0 (transmissiontest)",
+			"download-dir":        "/downloads",
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// AddTorrent stores a copy of torrent under a new id, which it returns.
+func (s *Server) AddTorrent(torrent transmission.Torrent) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	torrent.Id = s.nextId
+	s.nextId++
+	s.torrents[torrent.Id] = &torrent
+	return torrent.Id
+}
+
+// Torrents returns copies of the stored torrents, sorted by id.
+func (s *Server) Torrents() []transmission.Torrent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var torrents []transmission.Torrent
+	for _, torrent := range s.torrents {
+		torrents = append(torrents, *torrent)
+	}
+	sort.Slice(torrents, func(i, j int) bool { return torrents[i].Id < torrents[j].Id })
+	return torrents
+}
+
+// SetSessionField sets a field session-get returns, like "rpc-version".
+func (s *Server) SetSessionField(name string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session[name] = value
+}
+
+// SetLatency makes the server wait d before answering each request.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext makes the server answer the next request with the HTTP status and
+// body, before checking the session id. Calls queue up.
+func (s *Server) FailNext(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, body: body})
+}
+
+// SetResult makes the method answer with result instead of doing its work.
+// An empty result restores the method.
+func (s *Server) SetResult(method, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if result == "" {
+		delete(s.results, method)
+	} else {
+		s.results[method] = result
+	}
+}
+
+// ExpireSession makes the server hand out a new session id, as a restarted
+// daemon would.
+func (s *Server) ExpireSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionId = ""
+}
+
+// Requests returns the requests the server received, oldest first, including
+// those it answered with a 409 or a failure from FailNext.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Sessions returns how many session ids the server handed out.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions
+}
+
+type request struct {
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments"`
+	Tag       int             `json:"tag"`
+}
+
+type response struct {
+	Result    string      `json:"result"`
+	Arguments interface{} `json:"arguments,omitempty"`
+	Tag       int         `json:"tag,omitempty"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Reading the body first lets the server notice a client going away
+	// during the latency.
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var req request
+	decodeErr := json.Unmarshal(body, &req)
+
+	s.mu.Lock()
+	latency := s.latency
+	s.mu.Unlock()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{
+		Header:    r.Header.Clone(),
+		Method:    req.Method,
+		Arguments: append(json.RawMessage(nil), req.Arguments...),
+	})
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		w.WriteHeader(f.status)
+		fmt.Fprint(w, f.body)
+		return
+	}
+	if s.sessionId == "" {
+		s.sessions++
+		s.sessionId = fmt.Sprintf("transmissiontest-session-%d", s.sessions)
+	}
+	if r.Header.Get(csrfSessionHeader) != s.sessionId {
+		w.Header().Set(csrfSessionHeader, s.sessionId)
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "<h1>409: Conflict</h1><p>Your request had an invalid session-id header.</p>")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if decodeErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	resp := &response{Result: "success", Tag: req.Tag}
+	if result, ok := s.results[req.Method]; ok {
+		resp.Result = result
+	} else {
+		args, err := s.handle(req.Method, req.Arguments)
+		if err != nil {
+			resp.Result = err.Error()
+		} else {
+			resp.Arguments = args
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handle(method string, rawArgs json.RawMessage) (interface{}, error) {
+	var args struct {
+		Ids       interface{} `json:"ids"`
+		Fields    []string    `json:"fields"`
+		Filename  string      `json:"filename"`
+		Metainfo  string      `json:"metainfo"`
+		Paused    bool        `json:"paused"`
+		Directory string      `json:"download-dir"`
+	}
+	if len(rawArgs) > 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %v", err)
+		}
+	}
+	switch method {
+	case "session-get":
+		return s.session, nil
+	case "session-set":
+		var fields map[string]interface{}
+		if err := json.Unmarshal(rawArgs, &fields); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %v", err)
+		}
+		for name, value := range fields {
+			s.session[name] = value
+		}
+		return nil, nil
+	case "blocklist-update":
+		// The list comes from SetSessionField("blocklist-size", n).
+		return map[string]interface{}{"blocklist-size": s.session["blocklist-size"]}, nil
+	case "torrent-get":
+		result, err := s.get(s.selected(args.Ids), args.Fields)
+		if err == nil && args.Ids == "recently-active" {
+			result["removed"] = s.recentlyRemoved()
+		}
+		return result, err
+	case "torrent-add":
+		return s.add(args.Filename, args.Metainfo, args.Paused, args.Directory)
+	case "torrent-remove":
+		for _, torrent := range s.selected(args.Ids) {
+			delete(s.torrents, torrent.Id)
+			s.removed[torrent.Id] = time.Now()
+		}
+		return nil, nil
+	case "torrent-start", "torrent-start-now":
+		for _, torrent := range s.selected(args.Ids) {
+			torrent.Status = transmission.TR_STATUS_DOWNLOAD
+			if torrent.PercentDone >= 1 {
+				torrent.Status = transmission.TR_STATUS_SEED
+			}
+		}
+		return nil, nil
+	case "torrent-set":
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(rawArgs, &fields); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %v", err)
+		}
+		for _, torrent := range s.selected(args.Ids) {
+			if err := setTorrentFields(torrent, fields); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "torrent-stop":
+		for _, torrent := range s.selected(args.Ids) {
+			torrent.Status = transmission.TR_STATUS_STOPPED
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("method name not recognized")
+}
+
+// selected returns the torrents matching the ids argument, which may be
+// missing for all torrents, an id, a hash, "recently-active", which the fake
+// treats as all torrents, or a list of ids and hashes.
+func (s *Server) selected(ids interface{}) []*transmission.Torrent {
+	var list []interface{}
+	switch ids := ids.(type) {
+	case nil:
+	case []interface{}:
+		list = ids
+	case string:
+		if ids != "recently-active" {
+			list = []interface{}{ids}
+		}
+	default:
+		list = []interface{}{ids}
+	}
+	var torrents []*transmission.Torrent
+	for _, torrent := range s.torrents {
+		if list == nil {
+			torrents = append(torrents, torrent)
+			continue
+		}
+		for _, id := range list {
+			switch id := id.(type) {
+			case float64:
+				if int64(id) == torrent.Id {
+					torrents = append(torrents, torrent)
+				}
+			case string:
+				if strings.EqualFold(id, torrent.HashString) {
+					torrents = append(torrents, torrent)
+				}
+			}
+		}
+	}
+	sort.Slice(torrents, func(i, j int) bool { return torrents[i].Id < torrents[j].Id })
+	return torrents
+}
+
+func (s *Server) get(torrents []*transmission.Torrent, fields []string) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields specified")
+	}
+	objects := []map[string]interface{}{}
+	for _, torrent := range torrents {
+		all := torrentFields(torrent)
+		object := map[string]interface{}{}
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				object[field] = value
+			}
+		}
+		objects = append(objects, object)
+	}
+	return map[string]interface{}{"torrents": objects}, nil
+}
+
+// recentlyRemoved returns the ids of the torrents removed in the last
+// minute, sorted.
+func (s *Server) recentlyRemoved() []int64 {
+	removed := []int64{}
+	for id, when := range s.removed {
+		if time.Since(when) < time.Minute {
+			removed = append(removed, id)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	return removed
+}
+
+// torrentFields returns the fields of torrent by their JSON names. Like the
+// daemon, and unlike json.Marshal with the omitempty tags of Torrent, it
+// includes the fields with zero values.
+func torrentFields(torrent *transmission.Torrent) map[string]interface{} {
+	fields := map[string]interface{}{}
+	v := reflect.ValueOf(torrent).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		value := v.Field(i)
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			// The daemon sends empty lists, not null.
+			value = reflect.MakeSlice(value.Type(), 0, 0)
+		}
+		fields[name] = value.Interface()
+	}
+	return fields
+}
+
+// setTorrentFields sets the fields of torrent named in fields, the arguments
+// of a torrent-set. Arguments that are not torrent fields, like ids or
+// trackerAdd, are ignored.
+func setTorrentFields(torrent *transmission.Torrent, fields map[string]json.RawMessage) error {
+	v := reflect.ValueOf(torrent).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		value, ok := fields[name]
+		if !ok || name == "" || name == "id" {
+			continue
+		}
+		if err := json.Unmarshal(value, v.Field(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid argument %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) add(filename, metainfo string, paused bool, dir string) (interface{}, error) {
+	source := filename + metainfo
+	if source == "" {
+		return nil, fmt.Errorf("no filename or metainfo specified")
+	}
+	sum := sha1.Sum([]byte(source))
+	hash := hex.EncodeToString(sum[:])
+	for _, torrent := range s.torrents {
+		if torrent.HashString == hash {
+			return map[string]interface{}{"torrent-duplicate": brief(torrent)}, nil
+		}
+	}
+	name := "metainfo"
+	if filename != "" {
+		name = path.Base(filename)
+		if u, err := url.Parse(filename); err == nil && u.Scheme == "magnet" {
+			if dn := u.Query().Get("dn"); dn != "" {
+				name = dn
+			}
+		}
+	}
+	if dir == "" {
+		dir, _ = s.session["download-dir"].(string)
+	}
+	torrent := &transmission.Torrent{
+		Id:          s.nextId,
+		Name:        name,
+		HashString:  hash,
+		DownloadDir: dir,
+		Status:      transmission.TR_STATUS_DOWNLOAD,
+	}
+	if paused {
+		torrent.Status = transmission.TR_STATUS_STOPPED
+	}
+	s.nextId++
+	s.torrents[torrent.Id] = torrent
+	return map[string]interface{}{"torrent-added": brief(torrent)}, nil
+}
+
+// brief returns the fields torrent-add answers with.
+func brief(torrent *transmission.Torrent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         torrent.Id,
+		"name":       torrent.Name,
+		"hashString": torrent.HashString,
+	}
+}
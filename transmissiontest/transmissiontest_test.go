@@ -0,0 +1,192 @@
+package transmissiontest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	transmission "github.com/HawkMachine/transmission_go_api"
+	"github.com/HawkMachine/transmission_go_api/transmissiontest"
+)
+
+func newClient(t *testing.T, s *transmissiontest.Server, opts ...transmission.Option) *transmission.Transmission {
+	t.Helper()
+	client, err := transmission.NewWithOptions(s.URL, opts...)
+	if err != nil {
+		t.Fatalf("NewWithOptions: %v", err)
+	}
+	return client
+}
+
+func TestSessionHandshake(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got := s.Sessions(); got != 1 {
+		t.Errorf("Sessions() = %d, want 1", got)
+	}
+	// The first request is refused with a 409, the others carry the id.
+	requests := s.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	for i, req := range requests {
+		if req.Method != "session-get" {
+			t.Errorf("request %d method = %q, want session-get", i, req.Method)
+		}
+	}
+	if id := requests[0].Header.Get("X-Transmission-Session-Id"); id != "" {
+		t.Errorf("first request sent session id %q", id)
+	}
+
+	s.ExpireSession()
+	if _, err := client.GetSession(); err != nil {
+		t.Fatalf("GetSession after ExpireSession: %v", err)
+	}
+	if got := s.Sessions(); got != 2 {
+		t.Errorf("Sessions() after ExpireSession = %d, want 2", got)
+	}
+}
+
+func TestGetSendsZeroValues(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	id := s.AddTorrent(transmission.Torrent{Name: "stopped", Status: transmission.TR_STATUS_STOPPED})
+	client := newClient(t, s, transmission.WithStrictDecoding())
+
+	fields := []string{
+		transmission.FieldId,
+		transmission.FieldName,
+		transmission.FieldStatus,
+		transmission.FieldPercentDone,
+		transmission.FieldEta,
+		transmission.FieldIsFinished,
+		transmission.FieldLabels,
+	}
+	torrents, err := client.GetWithFields([]int64{id}, fields)
+	if err != nil {
+		t.Fatalf("GetWithFields: %v", err)
+	}
+	if len(torrents) != 1 || torrents[0].Name != "stopped" {
+		t.Fatalf("GetWithFields = %+v, want the stopped torrent", torrents)
+	}
+	if torrents[0].Labels == nil {
+		t.Errorf("Labels = nil, want empty")
+	}
+}
+
+func TestListAllStrict(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	s.AddTorrent(transmission.Torrent{Name: "a"})
+	s.AddTorrent(transmission.Torrent{Name: "b", Status: transmission.TR_STATUS_SEED, PercentDone: 1})
+	client := newClient(t, s, transmission.WithStrictDecoding())
+
+	torrents, err := client.ListAll()
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	if len(torrents) != 2 {
+		t.Errorf("ListAll returned %d torrents, want 2", len(torrents))
+	}
+}
+
+func TestTorrentActions(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	paused := true
+	added, err := client.AddWithOptions("magnet:?xt=urn:btih:abc&dn=ubuntu", &transmission.AddOptions{Paused: &paused})
+	if err != nil {
+		t.Fatalf("AddWithOptions: %v", err)
+	}
+	if added.Torrent.Name != "ubuntu" || added.Duplicate {
+		t.Errorf("AddWithOptions = %+v, want new torrent ubuntu", added)
+	}
+	id := added.Torrent.Id
+	if status := s.Torrents()[0].Status; status != transmission.TR_STATUS_STOPPED {
+		t.Errorf("status after paused add = %d, want stopped", status)
+	}
+
+	if err := client.Start([]int64{id}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if status := s.Torrents()[0].Status; status != transmission.TR_STATUS_DOWNLOAD {
+		t.Errorf("status after Start = %d, want downloading", status)
+	}
+	if err := client.Stop([]int64{id}); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if status := s.Torrents()[0].Status; status != transmission.TR_STATUS_STOPPED {
+		t.Errorf("status after Stop = %d, want stopped", status)
+	}
+
+	again, err := client.Add("magnet:?xt=urn:btih:abc&dn=ubuntu")
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if !again.Duplicate || again.Torrent.Id != id {
+		t.Errorf("Add of the same magnet = %+v, want duplicate of %d", again, id)
+	}
+
+	if err := client.Remove([]int64{id}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if n := len(s.Torrents()); n != 0 {
+		t.Errorf("%d torrents left after Remove", n)
+	}
+}
+
+func TestFailNextAndSetResult(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	s.FailNext(http.StatusBadGateway, "bad gateway")
+	var httpErr *transmission.HTTPError
+	if _, err := client.GetSession(); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("GetSession after FailNext = %v, want a 502 HTTPError", err)
+	}
+
+	s.SetResult("torrent-start", "no such torrent")
+	var rpcErr *transmission.RPCError
+	if err := client.Start([]int64{1}); !errors.As(err, &rpcErr) || rpcErr.Result != "no such torrent" {
+		t.Errorf("Start after SetResult = %v, want RPCError", err)
+	}
+	s.SetResult("torrent-start", "")
+	if err := client.Start([]int64{1}); err != nil {
+		t.Errorf("Start after SetResult reset: %v", err)
+	}
+}
+
+func TestTorrentSet(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+	a := s.AddTorrent(transmission.Torrent{Name: "a", DownloadLimit: 100, DownloadLimited: true})
+	b := s.AddTorrent(transmission.Torrent{Name: "b"})
+	client := newClient(t, s)
+
+	if err := client.SetDownloadLimit([]int64{a}, 0, false); err != nil {
+		t.Fatalf("SetDownloadLimit: %v", err)
+	}
+	if err := client.SetLabels([]int64{a, b}, []string{"linux"}); err != nil {
+		t.Fatalf("SetLabels: %v", err)
+	}
+	torrents := s.Torrents()
+	if torrents[0].DownloadLimit != 0 || torrents[0].DownloadLimited {
+		t.Errorf("torrent a has download limit %d, limited %v; want 0, false", torrents[0].DownloadLimit, torrents[0].DownloadLimited)
+	}
+	for _, torrent := range torrents {
+		if len(torrent.Labels) != 1 || torrent.Labels[0] != "linux" {
+			t.Errorf("torrent %s has labels %v, want [linux]", torrent.Name, torrent.Labels)
+		}
+	}
+}
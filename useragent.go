@@ -0,0 +1,50 @@
+package transmission_go_api
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+const modulePath = "github.com/HawkMachine/transmission_go_api"
+
+// DefaultUserAgent is the User-Agent header sent unless WithUserAgent is
+// given, like "transmission_go_api/v1.2.0". The version is the one the
+// program was built with, when known.
+var DefaultUserAgent = defaultUserAgent()
+
+func defaultUserAgent() string {
+	const name = "transmission_go_api"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return name
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				version = dep.Replace.Version
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return name
+	}
+	return name + "/" + version
+}
+
+// WithUserAgent makes the client send userAgent as its User-Agent header, for
+// example to tell tools apart in reverse proxy logs. To extend the default,
+// append DefaultUserAgent to it.
+func WithUserAgent(userAgent string) Option {
+	return func(c *config) error {
+		if userAgent == "" {
+			return fmt.Errorf("empty user agent")
+		}
+		c.userAgent = userAgent
+		return nil
+	}
+}